@@ -0,0 +1,278 @@
+package ethereal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ContractABI is a parsed contract ABI along with the JSON it was parsed from
+type ContractABI struct {
+	abi.ABI
+	Raw string
+}
+
+// ContractFunction represents a contract function definition
+type ContractFunction struct {
+	Name            string
+	Signature       string
+	Selector        string // 0x-prefixed 4-byte selector
+	StateMutability string
+	Inputs          []ContractParam
+	Outputs         []ContractParam
+}
+
+// ContractError represents a Solidity custom error definition
+type ContractError struct {
+	Name      string
+	Signature string
+	Selector  string // 0x-prefixed 4-byte selector
+	Inputs    []ContractParam
+}
+
+// ContractParam represents a function or error parameter
+type ContractParam struct {
+	Name       string
+	Type       string
+	Components []ContractParam
+}
+
+// ParseABI parses a JSON ABI
+func ParseABI(raw string) (*ContractABI, error) {
+	parsed, err := abi.JSON(strings.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return &ContractABI{ABI: parsed, Raw: raw}, nil
+}
+
+// MergeABIs combines ABIs into one. When several ABIs define the same function, event
+// or error signature, or a constructor, fallback or receive function, the first wins.
+func MergeABIs(abis ...*ContractABI) (*ContractABI, error) {
+	seen := make(map[string]bool)
+	var entries []json.RawMessage
+	for _, contractABI := range abis {
+		if contractABI == nil {
+			continue
+		}
+		raw, err := rawEntries(contractABI.Raw)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range raw {
+			key, err := abiEntryKey(entry)
+			if err != nil {
+				return nil, err
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			entries = append(entries, entry)
+		}
+	}
+
+	merged, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+	return ParseABI(string(merged))
+}
+
+// filterFunctions returns the ABI without the functions keep rejects
+func (c *ContractABI) filterFunctions(keep func(abi.Method) bool) (*ContractABI, error) {
+	raw, err := rawEntries(c.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []json.RawMessage
+	for _, entry := range raw {
+		parsed, err := abi.JSON(strings.NewReader("[" + string(entry) + "]"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ABI entry %s: %w", entry, err)
+		}
+		rejected := false
+		for _, method := range parsed.Methods {
+			rejected = !keep(method)
+		}
+		if !rejected {
+			entries = append(entries, entry)
+		}
+	}
+
+	filtered, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+	return ParseABI(string(filtered))
+}
+
+func rawEntries(raw string) ([]json.RawMessage, error) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse ABI JSON: %w", err)
+	}
+	return entries, nil
+}
+
+// abiEntryKey identifies an ABI JSON entry by its type and, for functions, events and
+// errors, its signature
+func abiEntryKey(entry json.RawMessage) (string, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(entry, &header); err != nil {
+		return "", fmt.Errorf("invalid ABI entry %s: %w", entry, err)
+	}
+	if header.Type == "constructor" || header.Type == "fallback" || header.Type == "receive" {
+		return header.Type, nil
+	}
+
+	parsed, err := abi.JSON(strings.NewReader("[" + string(entry) + "]"))
+	if err != nil {
+		return "", fmt.Errorf("invalid ABI entry %s: %w", entry, err)
+	}
+	for _, method := range parsed.Methods {
+		return "function " + method.Sig, nil
+	}
+	for _, event := range parsed.Events {
+		return "event " + event.Sig, nil
+	}
+	for _, abiErr := range parsed.Errors {
+		return "error " + abiErr.Sig, nil
+	}
+	return header.Type, nil
+}
+
+// Events returns all event definitions sorted by signature
+func (c *ContractABI) Events() []ContractEvent {
+	events := make([]ContractEvent, 0, len(c.ABI.Events))
+	for _, event := range c.ABI.Events {
+		inputs := make([]EventInput, len(event.Inputs))
+		for i, input := range event.Inputs {
+			inputs[i] = eventInput(input.Name, input.Type, input.Indexed)
+		}
+		events = append(events, ContractEvent{
+			Name:      event.RawName,
+			Signature: event.Sig,
+			Topic:     event.ID.Hex(),
+			Anonymous: event.Anonymous,
+			Inputs:    inputs,
+		})
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Signature < events[j].Signature })
+	return events
+}
+
+// Functions returns all function definitions sorted by signature
+func (c *ContractABI) Functions() []ContractFunction {
+	functions := make([]ContractFunction, 0, len(c.ABI.Methods))
+	for _, method := range c.ABI.Methods {
+		functions = append(functions, ContractFunction{
+			Name:            method.RawName,
+			Signature:       method.Sig,
+			Selector:        hexutil.Encode(method.ID),
+			StateMutability: method.StateMutability,
+			Inputs:          contractParams(method.Inputs),
+			Outputs:         contractParams(method.Outputs),
+		})
+	}
+	sort.Slice(functions, func(i, j int) bool { return functions[i].Signature < functions[j].Signature })
+	return functions
+}
+
+// Errors returns all custom error definitions sorted by signature
+func (c *ContractABI) Errors() []ContractError {
+	errs := make([]ContractError, 0, len(c.ABI.Errors))
+	for _, abiErr := range c.ABI.Errors {
+		errs = append(errs, ContractError{
+			Name:      abiErr.Name,
+			Signature: abiErr.Sig,
+			Selector:  hexutil.Encode(abiErr.ID[:4]),
+			Inputs:    contractParams(abiErr.Inputs),
+		})
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Signature < errs[j].Signature })
+	return errs
+}
+
+// EventsByName returns every event with the given name; overloads share a name
+func (c *ContractABI) EventsByName(name string) []abi.Event {
+	var events []abi.Event
+	for _, event := range c.ABI.Events {
+		if event.RawName == name || event.Sig == name {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Sig < events[j].Sig })
+	return events
+}
+
+// MethodsByName returns every function with the given name; overloads share a name
+func (c *ContractABI) MethodsByName(name string) []abi.Method {
+	var methods []abi.Method
+	for _, method := range c.ABI.Methods {
+		if method.RawName == name || method.Sig == name {
+			methods = append(methods, method)
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Sig < methods[j].Sig })
+	return methods
+}
+
+// Event returns the event with the given name or full signature, failing on ambiguous overloads
+func (c *ContractABI) Event(name string) (*abi.Event, error) {
+	events := c.EventsByName(name)
+	switch len(events) {
+	case 0:
+		return nil, fmt.Errorf("event %s does not exist in contract ABI", name)
+	case 1:
+		return &events[0], nil
+	default:
+		return nil, fmt.Errorf("event %s is overloaded, use its full signature, e.g. %s", name, events[0].Sig)
+	}
+}
+
+func contractParams(args abi.Arguments) []ContractParam {
+	params := make([]ContractParam, len(args))
+	for i, arg := range args {
+		params[i] = contractParam(arg.Name, arg.Type)
+	}
+	return params
+}
+
+func contractParam(name string, t abi.Type) ContractParam {
+	param := ContractParam{Name: name, Type: t.String()}
+	if tuple := tupleType(t); tuple != nil {
+		for i, elem := range tuple.TupleElems {
+			param.Components = append(param.Components, contractParam(tuple.TupleRawNames[i], *elem))
+		}
+	}
+	return param
+}
+
+func eventInput(name string, t abi.Type, indexed bool) EventInput {
+	input := EventInput{Name: name, Type: t.String(), Indexed: indexed}
+	if tuple := tupleType(t); tuple != nil {
+		for i, elem := range tuple.TupleElems {
+			input.Components = append(input.Components, eventInput(tuple.TupleRawNames[i], *elem, false))
+		}
+	}
+	return input
+}
+
+// tupleType returns the tuple type of t or of its (nested) array elements
+func tupleType(t abi.Type) *abi.Type {
+	for t.T == abi.SliceTy || t.T == abi.ArrayTy {
+		t = *t.Elem
+	}
+	if t.T == abi.TupleTy {
+		return &t
+	}
+	return nil
+}
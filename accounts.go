@@ -1,8 +1,30 @@
 package ethereal
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TransactionClient interface defines the RPC methods required to build transactions
+type TransactionClient interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+}
+
 // Accounts handles Ethereum account operations
 type Accounts struct {
-	// Add necessary fields here
+	client TransactionClient
+	fees   *FeeOracle
 }
 
 // NewAccounts creates a new Accounts instance
@@ -10,6 +32,12 @@ func NewAccounts() *Accounts {
 	return &Accounts{}
 }
 
+// SetTransactionBuilder configures the RPC client and fee oracle used by SignTransaction
+func (a *Accounts) SetTransactionBuilder(client TransactionClient, fees *FeeOracle) {
+	a.client = client
+	a.fees = fees
+}
+
 // DeriveAccount derives public and private key from a seed phrase
 func (a *Accounts) DeriveAccount(seedPhrase string, index int, passphrase string) (*Account, error) {
 	// Implementation here
@@ -47,10 +75,129 @@ func (a *Accounts) GetBalance(address string) (string, error) {
 	return "", nil
 }
 
-// SignTransaction signs a transaction with the account's private key
+// SignTransaction signs a transaction with the account's private key and returns it
+// RLP-encoded as hex. amount is in wei; an empty to creates a contract. Nonce and gas
+// limit come from the RPC client, fees from the standard tier of the fee oracle.
 func (a *Accounts) SignTransaction(account *Account, to string, amount string, data []byte) (string, error) {
-	// Implementation here
-	return "", nil
+	value := new(big.Int)
+	if amount != "" {
+		if _, ok := value.SetString(amount, 10); !ok {
+			return "", fmt.Errorf("invalid amount %q", amount)
+		}
+	}
+
+	var toAddress *common.Address
+	if to != "" {
+		if !common.IsHexAddress(to) {
+			return "", fmt.Errorf("invalid recipient address %q", to)
+		}
+		address := common.HexToAddress(to)
+		toAddress = &address
+	}
+
+	signed, err := a.buildTransaction(context.Background(), account, transactionRequest{to: toAddress, value: value, data: data})
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	return hexutil.Encode(raw), nil
+}
+
+// transactionRequest is a transaction to build; unset nonce, gas and fees are filled in
+type transactionRequest struct {
+	to                   *common.Address
+	value                *big.Int
+	data                 []byte
+	gas                  uint64
+	nonce                *uint64
+	maxFeePerGas         *big.Int
+	maxPriorityFeePerGas *big.Int
+	// abis decode custom errors when gas estimation reverts
+	abis []*ContractABI
+}
+
+// buildTransaction fills in a transaction request and signs it with the account's key
+func (a *Accounts) buildTransaction(ctx context.Context, account *Account, req transactionRequest) (*types.Transaction, error) {
+	if account == nil {
+		return nil, errors.New("account cannot be nil")
+	}
+	if a.client == nil || a.fees == nil {
+		return nil, errors.New("transaction builder not configured")
+	}
+
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(account.PrivateKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	value := req.value
+	if value == nil {
+		value = new(big.Int)
+	}
+
+	chainID, err := a.client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+
+	var nonce uint64
+	if req.nonce != nil {
+		nonce = *req.nonce
+	} else if nonce, err = a.client.PendingNonceAt(ctx, from); err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+
+	feeCap, tipCap := req.maxFeePerGas, req.maxPriorityFeePerGas
+	if feeCap == nil || tipCap == nil {
+		fees, err := a.fees.SuggestFees()
+		if err != nil {
+			return nil, fmt.Errorf("failed to suggest fees: %w", err)
+		}
+		if feeCap == nil {
+			feeCap = fees.Standard.MaxFeePerGas
+		}
+		if tipCap == nil {
+			tipCap = fees.Standard.MaxPriorityFeePerGas
+		}
+	}
+
+	gas := req.gas
+	if gas == 0 {
+		gas, err = a.client.EstimateGas(ctx, ethereum.CallMsg{
+			From:      from,
+			To:        req.to,
+			GasFeeCap: feeCap,
+			GasTipCap: tipCap,
+			Value:     value,
+			Data:      req.data,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate gas: %w", revertError(err, req.abis...))
+		}
+	}
+
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		GasTipCap: tipCap,
+		GasFeeCap: feeCap,
+		Gas:       gas,
+		To:        req.to,
+		Value:     value,
+		Data:      req.data,
+	})
+
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID(chainID), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return signed, nil
 }
 
 // VerifySignature verifies if a signature was signed by the given address
@@ -0,0 +1,328 @@
+package ethereal
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	defaultMaxMulticallData = 100 * 1024
+	defaultMaxBatchSize     = 100
+)
+
+// multicall3Address is where Multicall3 is deployed on most chains
+var multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+const multicall3ABI = `[{"type":"function","name":"aggregate3","stateMutability":"payable",
+	"inputs":[{"name":"calls","type":"tuple[]","components":[
+		{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],
+	"outputs":[{"name":"returnData","type":"tuple[]","components":[
+		{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]}]`
+
+var multicall3 = mustParseABI(multicall3ABI)
+
+// Error fragments nodes use when an aggregated call needs too much gas or is too large
+var multicallLimitErrors = []string{
+	"out of gas",
+	"gas required exceeds",
+	"gas limit",
+	"gas cap",
+	"request entity too large",
+	"payload too large",
+	"request too large",
+	"execution aborted (timeout",
+}
+
+// BatchCaller interface defines the method required for JSON-RPC batch requests.
+// rpc.Client satisfies it, and CallBatch finds it through ethclient.Client's Client().
+type BatchCaller interface {
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+// BatchConfig controls how CallBatch splits calls
+type BatchConfig struct {
+	// MaxMulticallData is the calldata size in bytes of one aggregate3 call
+	MaxMulticallData int
+	// MaxBatchSize is the number of calls per JSON-RPC batch without Multicall3
+	MaxBatchSize int
+	// DisableMulticall sends JSON-RPC batches even where Multicall3 is deployed
+	DisableMulticall bool
+}
+
+// CallRequest is one call of a batch
+type CallRequest struct {
+	Address string
+	Method  string // function name or full signature
+	Args    []interface{}
+}
+
+// BatchResult is the outcome of one call of a batch; reverts are *RevertError
+type BatchResult struct {
+	Result *CallResult
+	Err    error
+}
+
+// SetBatchConfig configures how CallBatch splits calls; zero values keep the defaults
+func (c *Contracts) SetBatchConfig(config BatchConfig) {
+	c.batchConfig = config
+}
+
+// preparedCall is an encoded call of a batch
+type preparedCall struct {
+	index  int
+	to     common.Address
+	data   []byte
+	method *abi.Method
+	abis   []*ContractABI
+}
+
+// CallBatch executes many read-only calls at the same block. Calls are aggregated into
+// Multicall3 aggregate3 calls where it is deployed, split by calldata size and again
+// when a node rejects an aggregate as too expensive, and otherwise sent as JSON-RPC
+// batches. Calls fail individually; the error is only set when the batch could not be sent.
+// opts.From is the sender of every call, which Multicall3 cannot preserve, so setting
+// it sends JSON-RPC batches.
+func (c *Contracts) CallBatch(calls []CallRequest, opts CallOpts) ([]BatchResult, error) {
+	state, ok := c.client.(StateClient)
+	if !ok {
+		return nil, errNoRPCClient
+	}
+	ctx := context.Background()
+
+	var from common.Address
+	if opts.From != "" {
+		if !common.IsHexAddress(opts.From) {
+			return nil, fmt.Errorf("invalid from address %s", opts.From)
+		}
+		from = common.HexToAddress(opts.From)
+	}
+
+	results := make([]BatchResult, len(calls))
+	var prepared []preparedCall
+	for i, call := range calls {
+		p, err := c.prepareCall(call, opts.ResolveProxy)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		p.index = i
+		prepared = append(prepared, p)
+	}
+	if len(prepared) == 0 {
+		return results, nil
+	}
+
+	block, err := c.resolveCallBlock(ctx, opts.Block)
+	if err != nil {
+		return nil, err
+	}
+
+	useMulticall := opts.From == "" && !c.batchConfig.DisableMulticall
+	if useMulticall {
+		// Multicall3 may be deployed after the block; blocks by hash are checked at the latest block
+		code, err := state.CodeAt(ctx, multicall3Address, block.number)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for Multicall3: %w", err)
+		}
+		useMulticall = len(code) > 0
+	}
+
+	if useMulticall {
+		err = c.multicall(ctx, prepared, block, results)
+	} else {
+		err = c.batchCalls(ctx, prepared, from, block, results)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (c *Contracts) prepareCall(call CallRequest, resolveProxy bool) (preparedCall, error) {
+	if !common.IsHexAddress(call.Address) {
+		return preparedCall{}, fmt.Errorf("invalid address %s", call.Address)
+	}
+	contractABI, err := c.GetABI(call.Address, resolveProxy)
+	if err != nil {
+		return preparedCall{}, fmt.Errorf("failed to get ABI: %w", err)
+	}
+	method, converted, err := contractABI.resolveMethod(call.Method, call.Args, nil)
+	if err != nil {
+		return preparedCall{}, err
+	}
+	input, err := method.Inputs.Pack(converted...)
+	if err != nil {
+		return preparedCall{}, fmt.Errorf("failed to encode arguments of %s: %w", method.Sig, err)
+	}
+	return preparedCall{
+		to:     common.HexToAddress(call.Address),
+		data:   append(append([]byte{}, method.ID...), input...),
+		method: method,
+		abis:   []*ContractABI{contractABI},
+	}, nil
+}
+
+// multicall sends calls as aggregate3 calls of at most MaxMulticallData bytes
+func (c *Contracts) multicall(ctx context.Context, calls []preparedCall, block blockRef, results []BatchResult) error {
+	limit := c.batchConfig.MaxMulticallData
+	if limit <= 0 {
+		limit = defaultMaxMulticallData
+	}
+
+	var chunk []preparedCall
+	size := 0
+	for _, call := range calls {
+		// Each call takes its padded calldata plus offsets, target, flag and length
+		callSize := (len(call.data)+31)/32*32 + 5*32
+		if len(chunk) > 0 && size+callSize > limit {
+			if err := c.aggregate(ctx, chunk, block, results); err != nil {
+				return err
+			}
+			chunk, size = nil, 0
+		}
+		chunk = append(chunk, call)
+		size += callSize
+	}
+	return c.aggregate(ctx, chunk, block, results)
+}
+
+// multicall3Call is Multicall3's Call3 struct
+type multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// aggregate sends one aggregate3 call, bisecting it when the node rejects it as too large
+func (c *Contracts) aggregate(ctx context.Context, calls []preparedCall, block blockRef, results []BatchResult) error {
+	method := multicall3.Methods["aggregate3"]
+	args := make([]multicall3Call, len(calls))
+	for i, call := range calls {
+		args[i] = multicall3Call{Target: call.to, AllowFailure: true, CallData: call.data}
+	}
+	input, err := method.Inputs.Pack(args)
+	if err != nil {
+		return fmt.Errorf("failed to encode aggregate3: %w", err)
+	}
+
+	to := multicall3Address
+	msg := ethereum.CallMsg{To: &to, Data: append(append([]byte{}, method.ID...), input...)}
+	state := c.client.(StateClient)
+	output, err := c.callAt(ctx, state, msg, block)
+	if err != nil {
+		if !isMulticallLimitError(err) {
+			return fmt.Errorf("aggregate3 call failed: %w", err)
+		}
+		if len(calls) == 1 {
+			// The call is too expensive even on its own
+			results[calls[0].index].Err = err
+			return nil
+		}
+		mid := len(calls) / 2
+		if err := c.aggregate(ctx, calls[:mid], block, results); err != nil {
+			return err
+		}
+		return c.aggregate(ctx, calls[mid:], block, results)
+	}
+
+	values, err := method.Outputs.Unpack(output)
+	if err != nil {
+		return fmt.Errorf("failed to decode aggregate3 result: %w", err)
+	}
+	returned := reflect.ValueOf(values[0])
+	if returned.Len() != len(calls) {
+		return fmt.Errorf("aggregate3 returned %d results for %d calls", returned.Len(), len(calls))
+	}
+	for i, call := range calls {
+		fields := tupleValues(returned.Index(i).Interface())
+		success, data := fields[0].(bool), fields[1].([]byte)
+		if !success {
+			results[call.index].Err = DecodeRevert(data, call.abis...)
+			continue
+		}
+		results[call.index].Result, results[call.index].Err = decodeCallResult(call.method, data)
+	}
+	return nil
+}
+
+func isMulticallLimitError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range multicallLimitErrors {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// batchCaller finds JSON-RPC batch support on the client
+func (c *Contracts) batchCaller() (BatchCaller, bool) {
+	if caller, ok := c.client.(BatchCaller); ok {
+		return caller, true
+	}
+	if provider, ok := c.client.(interface{ Client() *rpc.Client }); ok && provider.Client() != nil {
+		return provider.Client(), true
+	}
+	return nil, false
+}
+
+// batchCalls sends calls as JSON-RPC batches of eth_call, or one by one when the
+// client cannot send batches
+func (c *Contracts) batchCalls(ctx context.Context, calls []preparedCall, from common.Address, block blockRef, results []BatchResult) error {
+	caller, ok := c.batchCaller()
+	if !ok {
+		state := c.client.(StateClient)
+		for _, call := range calls {
+			to := call.to
+			msg := ethereum.CallMsg{From: from, To: &to, Data: call.data}
+			output, err := c.callAt(ctx, state, msg, block)
+			c.setBatchResult(results, call, output, err)
+		}
+		return nil
+	}
+
+	size := c.batchConfig.MaxBatchSize
+	if size <= 0 {
+		size = defaultMaxBatchSize
+	}
+	for start := 0; start < len(calls); start += size {
+		end := start + size
+		if end > len(calls) {
+			end = len(calls)
+		}
+		chunk := calls[start:end]
+
+		elems := make([]rpc.BatchElem, len(chunk))
+		outputs := make([]hexutil.Bytes, len(chunk))
+		for i, call := range chunk {
+			arg := map[string]interface{}{"to": call.to, "data": hexutil.Bytes(call.data)}
+			if from != (common.Address{}) {
+				arg["from"] = from
+			}
+			elems[i] = rpc.BatchElem{Method: "eth_call", Args: []interface{}{arg, block.rpcArg()}, Result: &outputs[i]}
+		}
+		if err := caller.BatchCallContext(ctx, elems); err != nil {
+			return fmt.Errorf("batch request failed: %w", err)
+		}
+		for i, call := range chunk {
+			c.setBatchResult(results, call, outputs[i], elems[i].Error)
+		}
+	}
+	return nil
+}
+
+func (c *Contracts) setBatchResult(results []BatchResult, call preparedCall, output []byte, err error) {
+	if err != nil {
+		results[call.index].Err = revertError(err, call.abis...)
+		return
+	}
+	results[call.index].Result, results[call.index].Err = decodeCallResult(call.method, output)
+}
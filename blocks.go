@@ -0,0 +1,111 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// SetTimestampResolver sets how time bounds of an EventFilter are converted to blocks
+func (c *Contracts) SetTimestampResolver(resolver TimestampResolver) {
+	c.blocks = resolver
+}
+
+// blockRange resolves the bounds of a filter; a nil ToBlock means the latest block
+func (c *Contracts) blockRange(ctx context.Context, filter EventFilter) (*big.Int, *big.Int, error) {
+	from, err := c.blockBound(ctx, filter.FromBlock, "after")
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid fromBlock: %w", err)
+	}
+	if from == nil {
+		from = big.NewInt(0)
+	}
+
+	to, err := c.blockBound(ctx, filter.ToBlock, "before")
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid toBlock: %w", err)
+	}
+
+	if to != nil && from.Cmp(to) > 0 {
+		return nil, nil, errors.New("fromBlock cannot be greater than toBlock")
+	}
+	return from, to, nil
+}
+
+// blockBound resolves a block number, block tag, time.Time or RFC3339 string to a block number
+func (c *Contracts) blockBound(ctx context.Context, bound interface{}, closest string) (*big.Int, error) {
+	switch v := bound.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		return c.blockAtTime(v, closest)
+	case *time.Time:
+		if v == nil {
+			return nil, nil
+		}
+		return c.blockAtTime(*v, closest)
+	case string:
+		return c.blockString(ctx, v, closest)
+	}
+
+	number, err := toBigInt(bound)
+	if err != nil {
+		return nil, err
+	}
+	if number.Sign() < 0 {
+		return nil, fmt.Errorf("negative block number %s", number)
+	}
+	return number, nil
+}
+
+func (c *Contracts) blockString(ctx context.Context, bound string, closest string) (*big.Int, error) {
+	switch strings.ToLower(strings.TrimSpace(bound)) {
+	case "", "latest":
+		return nil, nil
+	case "earliest":
+		return big.NewInt(0), nil
+	case "safe":
+		return c.taggedBlock(ctx, rpc.SafeBlockNumber)
+	case "finalized":
+		return c.taggedBlock(ctx, rpc.FinalizedBlockNumber)
+	case "pending":
+		return nil, errors.New("pending logs cannot be queried by range")
+	}
+
+	if t, err := time.Parse(time.RFC3339, bound); err == nil {
+		return c.blockAtTime(t, closest)
+	}
+
+	number, ok := new(big.Int).SetString(strings.TrimSpace(bound), 0)
+	if !ok || number.Sign() < 0 {
+		return nil, fmt.Errorf("%q is not a block number, block tag or RFC3339 time", bound)
+	}
+	return number, nil
+}
+
+func (c *Contracts) taggedBlock(ctx context.Context, tag rpc.BlockNumber) (*big.Int, error) {
+	if c.client == nil {
+		return nil, errNoRPCClient
+	}
+	header, err := c.client.HeaderByNumber(ctx, big.NewInt(tag.Int64()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s block: %w", tag.String(), err)
+	}
+	return header.Number, nil
+}
+
+func (c *Contracts) blockAtTime(t time.Time, closest string) (*big.Int, error) {
+	if c.blocks == nil {
+		return nil, errors.New("no timestamp resolver configured")
+	}
+	number, err := c.blocks.GetBlockByTimestamp(t.Unix(), closest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve block at %s: %w", t.Format(time.RFC3339), err)
+	}
+	return big.NewInt(number), nil
+}
@@ -6,7 +6,8 @@ import (
 	"time"
 )
 
-// CacheItem represents a single cached item with expiration
+// CacheItem represents a single cached item with expiration.
+// A zero Expiration means the item never expires.
 type CacheItem struct {
 	Value      interface{}
 	Expiration time.Time
@@ -37,7 +38,7 @@ func (c *Cache) Get(key string) (interface{}, error) {
 		return nil, errors.New("key not found in cache")
 	}
 
-	if time.Now().After(item.Expiration) {
+	if item.expired(time.Now()) {
 		delete(c.items, key)
 		return nil, errors.New("cache item expired")
 	}
@@ -61,6 +62,19 @@ func (c *Cache) Set(key string, value interface{}) error {
 	return nil
 }
 
+// SetPermanent stores a value in cache without expiration, for immutable data
+func (c *Cache) SetPermanent(key string, value interface{}) error {
+	if value == nil {
+		return errors.New("cannot cache nil value")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items[key] = CacheItem{Value: value}
+	return nil
+}
+
 // Delete removes a specific key from the cache
 func (c *Cache) Delete(key string) {
 	c.mu.Lock()
@@ -82,8 +96,12 @@ func (c *Cache) Cleanup() {
 
 	now := time.Now()
 	for key, item := range c.items {
-		if now.After(item.Expiration) {
+		if item.expired(now) {
 			delete(c.items, key)
 		}
 	}
 }
+
+func (i CacheItem) expired(now time.Time) bool {
+	return !i.Expiration.IsZero() && now.After(i.Expiration)
+}
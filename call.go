@@ -0,0 +1,196 @@
+package ethereal
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BlockHashCaller interface defines the method required for eth_call at a block hash
+type BlockHashCaller interface {
+	CallContractAtHash(ctx context.Context, call ethereum.CallMsg, blockHash common.Hash) ([]byte, error)
+}
+
+// CallOpts configures a read-only contract call
+type CallOpts struct {
+	// Block is a block number, a 0x-prefixed block hash, a tag ("latest", "pending",
+	// "safe", "finalized", "earliest") or a time as accepted by EventFilter; nil for latest
+	Block interface{}
+	// From is the sender of the call, for functions that depend on msg.sender
+	From string
+	// ResolveProxy calls the function through the implementation ABI of a proxy
+	ResolveProxy bool
+}
+
+// CallResult holds the decoded outputs of a contract call
+type CallResult struct {
+	Signature string
+	// Outputs are in declaration order; unnamed outputs are named arg0, arg1 and so on
+	Outputs []FunctionParam
+	Data    []byte // the raw return data
+}
+
+// Values returns the outputs by name
+func (r *CallResult) Values() map[string]interface{} {
+	values := make(map[string]interface{}, len(r.Outputs))
+	for _, output := range r.Outputs {
+		values[output.Name] = output.Value
+	}
+	return values
+}
+
+// Call executes a read-only call of a function with eth_call and decodes its outputs.
+// method is a function name or a full signature to pick an overload, and args are
+// converted as by EncodeFunctionCall. Reverts are returned as a *RevertError.
+func (c *Contracts) Call(address string, method string, opts CallOpts, args ...interface{}) (*CallResult, error) {
+	state, ok := c.client.(StateClient)
+	if !ok {
+		return nil, errNoRPCClient
+	}
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid address %s", address)
+	}
+
+	contractABI, err := c.GetABI(address, opts.ResolveProxy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ABI: %w", err)
+	}
+
+	abiMethod, converted, err := contractABI.resolveMethod(method, args, nil)
+	if err != nil {
+		return nil, err
+	}
+	input, err := abiMethod.Inputs.Pack(converted...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments of %s: %w", abiMethod.Sig, err)
+	}
+
+	to := common.HexToAddress(address)
+	msg := ethereum.CallMsg{To: &to, Data: append(append([]byte{}, abiMethod.ID...), input...)}
+	if opts.From != "" {
+		if !common.IsHexAddress(opts.From) {
+			return nil, fmt.Errorf("invalid from address %s", opts.From)
+		}
+		msg.From = common.HexToAddress(opts.From)
+	}
+
+	ctx := context.Background()
+	block, err := c.resolveCallBlock(ctx, opts.Block)
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.callAt(ctx, state, msg, block)
+	if err != nil {
+		return nil, revertError(err, append([]*ContractABI{contractABI}, c.errorABIs(address)...)...)
+	}
+
+	return decodeCallResult(abiMethod, output)
+}
+
+func decodeCallResult(method *abi.Method, output []byte) (*CallResult, error) {
+	if len(output) == 0 && len(method.Outputs) > 0 {
+		return nil, fmt.Errorf("call to %s returned no data, the address may not be a contract at that block", method.Sig)
+	}
+
+	values, err := method.Outputs.Unpack(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode outputs of %s: %w", method.Sig, err)
+	}
+
+	outputs := namedArguments(method.Outputs)
+	result := &CallResult{Signature: method.Sig, Outputs: make([]FunctionParam, len(outputs)), Data: output}
+	for i, out := range outputs {
+		result.Outputs[i] = FunctionParam{Name: out.Name, Type: out.Type.String(), Value: values[i]}
+	}
+	return result, nil
+}
+
+// blockRef is the resolved block of a call: a hash, or a number that is nil for the
+// latest block and negative for tags
+type blockRef struct {
+	hash   *common.Hash
+	number *big.Int
+}
+
+func (c *Contracts) resolveCallBlock(ctx context.Context, block interface{}) (blockRef, error) {
+	if hash, ok := blockHash(block); ok {
+		return blockRef{hash: &hash}, nil
+	}
+	number, err := c.callBlock(ctx, block)
+	if err != nil {
+		return blockRef{}, err
+	}
+	return blockRef{number: number}, nil
+}
+
+// rpcArg is the block parameter of eth_call as ethclient sends it
+func (b blockRef) rpcArg() interface{} {
+	switch {
+	case b.hash != nil:
+		return rpc.BlockNumberOrHashWithHash(*b.hash, false)
+	case b.number == nil:
+		return "latest"
+	case b.number.Sign() >= 0:
+		return hexutil.EncodeBig(b.number)
+	default:
+		return rpc.BlockNumber(b.number.Int64()).String()
+	}
+}
+
+// callAt runs eth_call at a resolved block
+func (c *Contracts) callAt(ctx context.Context, state StateClient, msg ethereum.CallMsg, block blockRef) ([]byte, error) {
+	if block.hash != nil {
+		caller, ok := c.client.(BlockHashCaller)
+		if !ok {
+			return nil, fmt.Errorf("client does not support calls at block hash %s", block.hash.Hex())
+		}
+		return caller.CallContractAtHash(ctx, msg, *block.hash)
+	}
+	return state.CallContract(ctx, msg, block.number)
+}
+
+// callBlock resolves the block of a call. Tags the node understands are passed on as
+// the negative numbers ethclient sends as tags.
+func (c *Contracts) callBlock(ctx context.Context, block interface{}) (*big.Int, error) {
+	if tag, ok := block.(string); ok {
+		switch strings.ToLower(strings.TrimSpace(tag)) {
+		case "pending":
+			return big.NewInt(int64(rpc.PendingBlockNumber)), nil
+		case "safe":
+			return big.NewInt(int64(rpc.SafeBlockNumber)), nil
+		case "finalized":
+			return big.NewInt(int64(rpc.FinalizedBlockNumber)), nil
+		}
+	}
+	number, err := c.blockBound(ctx, block, "before")
+	if err != nil {
+		return nil, fmt.Errorf("invalid block: %w", err)
+	}
+	return number, nil
+}
+
+func blockHash(block interface{}) (common.Hash, bool) {
+	switch v := block.(type) {
+	case common.Hash:
+		return v, true
+	case *common.Hash:
+		if v != nil {
+			return *v, true
+		}
+	case string:
+		v = strings.TrimSpace(v)
+		if len(v) == 66 && strings.HasPrefix(v, "0x") {
+			if _, err := hexutil.Decode(v); err == nil {
+				return common.HexToHash(v), true
+			}
+		}
+	}
+	return common.Hash{}, false
+}
@@ -1,9 +1,20 @@
 package ethereal
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	errNoRPCClient   = errors.New("no RPC client configured")
+	errNoABIProvider = errors.New("no ABI provider configured")
 )
 
 // Etherscan interface defines the methods required from an Etherscan client
@@ -12,23 +23,44 @@ type EtherscanClient interface {
 	GetContractSource(address string) (string, error)
 }
 
+// ChainClient interface defines the methods required from an Ethereum RPC client
+type ChainClient interface {
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
 // CacheClient interface defines the methods required from a cache implementation
 type CacheClient interface {
 	Get(key string) (interface{}, error)
 	Set(key string, value interface{}) error
 }
 
+// TimestampResolver converts a Unix timestamp to a block number. closest is
+// "before" or "after", as in Etherscan's getblocknobytime.
+type TimestampResolver interface {
+	GetBlockByTimestamp(timestamp int64, closest string) (int64, error)
+}
+
 // EventFilter defines parameters for filtering contract events
 type EventFilter struct {
-	FromBlock uint64
-	ToBlock   uint64
+	// FromBlock and ToBlock are inclusive bounds given as a block number, a block tag
+	// ("earliest", "latest", "safe", "finalized"), a time.Time or an RFC3339 string.
+	// Times resolve to the first block after FromBlock and the last block before ToBlock.
+	// A nil FromBlock starts at genesis and a nil ToBlock ends at the latest block.
+	FromBlock interface{}
+	ToBlock   interface{}
 	Address   string
 	Topics    []string
+	// ArgumentFilters maps event argument names to a value or a slice of accepted values.
+	// Indexed arguments are filtered by the node, the others after decoding.
+	ArgumentFilters map[string]interface{}
 }
 
 // ContractEvent represents a contract event definition
 type ContractEvent struct {
 	Name      string
+	Signature string
+	Topic     string // 0x-prefixed topic0, the keccak256 hash of the signature
 	Anonymous bool
 	Inputs    []EventInput
 }
@@ -45,7 +77,14 @@ type EventInput struct {
 type ProxyInfo struct {
 	IsProxy        bool
 	Implementation string
-	ProxyType      string // "EIP1967", "EIP897", "Custom"
+	ProxyType      string // "EIP1967", "EIP1967Beacon", "EIP1822", "EIP1167", "ZeppelinOS", "GnosisSafe", "EIP897", "EIP2535"
+	Admin          string // EIP-1967 admin, if set
+	Beacon         string // EIP-1967 beacon the implementation is read from, if set
+	// Chain lists the implementations followed when the implementation is a proxy
+	// itself, ending with Implementation
+	Chain []string
+	// Facets are set for EIP-2535 diamonds, which have no single implementation
+	Facets []Facet
 }
 
 // FunctionCall represents a contract function call
@@ -64,28 +103,67 @@ type FunctionParam struct {
 // Contracts handles Ethereum contract operations
 type Contracts struct {
 	etherscan EtherscanClient
+	client    ChainClient
 	cache     CacheClient
+	blocks    TimestampResolver
+	accounts  *Accounts
+	logConfig LogFetchConfig
+
+	subscriptionConfig SubscriptionConfig
+	batchConfig        BatchConfig
+	watcherConfig      WatcherConfig
 }
 
 // NewContracts creates a new Contracts instance
-func NewContracts(etherscan EtherscanClient, cache CacheClient) *Contracts {
+func NewContracts(etherscan EtherscanClient, client ChainClient, cache CacheClient) *Contracts {
 	return &Contracts{
 		etherscan: etherscan,
+		client:    client,
 		cache:     cache,
 	}
 }
 
 // GetABI retrieves and parses the ABI for a contract
-func (c *Contracts) GetABI(address string, resolveProxy bool) ([]map[string]interface{}, error) {
+func (c *Contracts) GetABI(address string, resolveProxy bool) (*ContractABI, error) {
 	if address == "" {
 		return nil, errors.New("address cannot be empty")
 	}
 
 	cacheKey := fmt.Sprintf("abi_%s_%v", address, resolveProxy)
 
+	// Diamonds change with DiamondCut, which makes a cached ABI stale
+	refreshed := false
+	if resolveProxy {
+		var err error
+		if refreshed, err = c.refreshDiamond(address); err != nil {
+			return nil, err
+		}
+	}
+
 	// Try to get from cache first
-	if cached, err := c.cache.Get(cacheKey); err == nil {
-		return cached.([]map[string]interface{}), nil
+	if cached, err := c.cache.Get(cacheKey); err == nil && !refreshed {
+		return cached.(*ContractABI), nil
+	}
+
+	contractABI, err := c.fetchABI(address)
+	if resolveProxy {
+		contractABI, err = c.resolveProxyABI(address, contractABI, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache the result
+	if err := c.cache.Set(cacheKey, contractABI); err != nil {
+		return nil, fmt.Errorf("failed to cache ABI: %w", err)
+	}
+
+	return contractABI, nil
+}
+
+func (c *Contracts) fetchABI(address string) (*ContractABI, error) {
+	if c.etherscan == nil {
+		return nil, errNoABIProvider
 	}
 
 	// Get ABI from Etherscan
@@ -95,76 +173,102 @@ func (c *Contracts) GetABI(address string, resolveProxy bool) ([]map[string]inte
 	}
 
 	// Parse ABI
-	var abiArray []map[string]interface{}
-	if err := json.Unmarshal([]byte(abiString), &abiArray); err != nil {
+	contractABI, err := ParseABI(abiString)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse ABI: %w", err)
 	}
+	return contractABI, nil
+}
 
-	// Cache the result
-	if err := c.cache.Set(cacheKey, abiArray); err != nil {
-		return nil, fmt.Errorf("failed to cache ABI: %w", err)
+// ListEvents returns all events defined in the contract with their signatures and topics
+func (c *Contracts) ListEvents(address string, resolveProxy bool) ([]ContractEvent, error) {
+	contractABI, err := c.GetABI(address, resolveProxy)
+	if err != nil {
+		return nil, err
 	}
+	return contractABI.Events(), nil
+}
 
-	return abiArray, nil
+// ListFunctions returns all functions defined in the contract with their signatures and selectors
+func (c *Contracts) ListFunctions(address string, resolveProxy bool) ([]ContractFunction, error) {
+	contractABI, err := c.GetABI(address, resolveProxy)
+	if err != nil {
+		return nil, err
+	}
+	return contractABI.Functions(), nil
 }
 
-// ListEvents returns all event names defined in the contract
-func (c *Contracts) ListEvents(address string, resolveProxy bool) ([]string, error) {
-	abiMap, err := c.GetABI(address, resolveProxy)
+// ListErrors returns all custom errors defined in the contract with their signatures and selectors
+func (c *Contracts) ListErrors(address string, resolveProxy bool) ([]ContractError, error) {
+	contractABI, err := c.GetABI(address, resolveProxy)
 	if err != nil {
 		return nil, err
 	}
+	return contractABI.Errors(), nil
+}
 
-	var events []string
-	for name, item := range abiMap {
-		if itemMap, ok := item.(map[string]interface{}); ok {
-			if itemMap["type"] == "event" {
-				events = append(events, name)
-			}
+// GetEvents retrieves and decodes event logs emitted by a contract.
+// filter.Topics constrain topic1-3, with "" as a wildcard. With resolveProxy, the events
+// of an upgradeable proxy are decoded with the implementation ABI of their block.
+func (c *Contracts) GetEvents(address string, event string, filter EventFilter, resolveProxy bool) ([]EventRecord, error) {
+	if resolveProxy && address != "" && event != "" {
+		if records, ok, err := c.historicalEvents(address, event, filter); ok || err != nil {
+			return records, err
 		}
 	}
 
-	return events, nil
+	abiEvent, query, dataFilters, err := c.prepareEventQuery(address, event, filter, resolveProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	query.FromBlock, query.ToBlock, err = c.blockRange(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := c.fetchLogs(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.decodeEventLogs(abiEvent, logs, dataFilters)
 }
 
-// GetEvents retrieves events from a contract
-func (c *Contracts) GetEvents(address string, event string, filter EventFilter, resolveProxy bool) ([]interface{}, error) {
+// prepareEventQuery validates an event request and builds its query without a block range
+func (c *Contracts) prepareEventQuery(address string, event string, filter EventFilter, resolveProxy bool) (*abi.Event, ethereum.FilterQuery, []argumentFilter, error) {
+	var query ethereum.FilterQuery
 	if address == "" {
-		return nil, errors.New("address cannot be empty")
+		return nil, query, nil, errors.New("address cannot be empty")
 	}
 	if event == "" {
-		return nil, errors.New("event name cannot be empty")
-	}
-
-	// Validate filter parameters
-	if filter.FromBlock > filter.ToBlock && filter.ToBlock != 0 {
-		return nil, errors.New("fromBlock cannot be greater than toBlock")
+		return nil, query, nil, errors.New("event name cannot be empty")
 	}
 
 	// Get ABI to validate event exists
-	abi, err := c.GetABI(address, resolveProxy)
+	contractABI, err := c.GetABI(address, resolveProxy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get ABI: %w", err)
+		return nil, query, nil, fmt.Errorf("failed to get ABI: %w", err)
 	}
 
 	// Verify event exists in ABI
-	eventExists := false
-	for _, item := range abi {
-		if itemMap, ok := item.(map[string]interface{}); ok {
-			if itemMap["type"] == "event" && itemMap["name"] == event {
-				eventExists = true
-				break
-			}
-		}
+	abiEvent, err := contractABI.Event(event)
+	if err != nil {
+		return nil, query, nil, err
 	}
 
-	if !eventExists {
-		return nil, fmt.Errorf("event %s does not exist in contract ABI", event)
+	if c.client == nil {
+		return nil, query, nil, errNoRPCClient
+	}
+	if !common.IsHexAddress(address) {
+		return nil, query, nil, fmt.Errorf("invalid address %s", address)
 	}
 
-	// Implementation would need to use etherscan API or direct blockchain connection
-	// to fetch the actual events. This is a placeholder that returns an empty slice.
-	return []interface{}{}, nil
+	query, dataFilters, err := eventQuery(common.HexToAddress(address), abiEvent, filter)
+	if err != nil {
+		return nil, query, nil, err
+	}
+	return abiEvent, query, dataFilters, nil
 }
 
 // GetFunctionSignature returns the function signature for a given function name
@@ -176,37 +280,18 @@ func (c *Contracts) GetFunctionSignature(address string, functionName string, re
 		return "", errors.New("function name cannot be empty")
 	}
 
-	abi, err := c.GetABI(address, resolveProxy)
+	contractABI, err := c.GetABI(address, resolveProxy)
 	if err != nil {
 		return "", fmt.Errorf("failed to get ABI: %w", err)
 	}
 
-	for _, item := range abi {
-		if itemMap, ok := item.(map[string]interface{}); ok {
-			if itemMap["type"] == "function" && itemMap["name"] == functionName {
-				inputs, ok := itemMap["inputs"].([]interface{})
-				if !ok {
-					return "", errors.New("invalid ABI format: inputs not found")
-				}
-
-				signature := functionName + "("
-				for i, input := range inputs {
-					inputMap, ok := input.(map[string]interface{})
-					if !ok {
-						return "", errors.New("invalid ABI format: input type not found")
-					}
-					if i > 0 {
-						signature += ","
-					}
-					signature += inputMap["type"].(string)
-				}
-				signature += ")"
-				return signature, nil
-			}
-		}
+	methods := contractABI.MethodsByName(functionName)
+	if len(methods) == 0 {
+		return "", fmt.Errorf("function %s not found in contract ABI", functionName)
 	}
 
-	return "", fmt.Errorf("function %s not found in contract ABI", functionName)
+	// Overloads are sorted by signature, so the result is stable
+	return methods[0].Sig, nil
 }
 
 // IsContract checks if the given address is a contract
@@ -222,6 +307,10 @@ func (c *Contracts) IsContract(address string) (bool, error) {
 		return cached.(bool), nil
 	}
 
+	if c.etherscan == nil {
+		return false, errNoABIProvider
+	}
+
 	// Get contract source code from Etherscan
 	// If it returns source code, it's a contract
 	abi, err := c.etherscan.GetContractABI(address)
@@ -235,7 +324,11 @@ func (c *Contracts) IsContract(address string) (bool, error) {
 	return isContract, nil
 }
 
-// GetProxyInfo checks if a contract is a proxy and returns its implementation
+// GetProxyInfo checks if a contract is a proxy and returns its implementation. EIP-1167
+// clones are recognized by their bytecode; EIP-1967 (including beacon and UUPS proxies),
+// EIP-1822, legacy ZeppelinOS and Gnosis Safe proxies by their storage, and EIP-897
+// proxies by implementation(). Chains of proxies are followed to the final implementation.
+// EIP-2535 diamonds are enumerated through facets() and report their Facets instead.
 func (c *Contracts) GetProxyInfo(address string) (*ProxyInfo, error) {
 	if address == "" {
 		return nil, errors.New("address cannot be empty")
@@ -248,29 +341,17 @@ func (c *Contracts) GetProxyInfo(address string) (*ProxyInfo, error) {
 		return cached.(*ProxyInfo), nil
 	}
 
-	// Get contract source code to check for proxy patterns
-	source, err := c.etherscan.GetContractSource(address)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get contract source: %w", err)
-	}
-
-	info := &ProxyInfo{
-		IsProxy: false,
+	state, ok := c.client.(StateClient)
+	if !ok {
+		return nil, errNoRPCClient
 	}
-
-	// Check for EIP-1967 proxy
-	if containsEIP1967Pattern(source) {
-		info.IsProxy = true
-		info.ProxyType = "EIP1967"
-		// Implementation would need to read the implementation slot
-		// 0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid address %s", address)
 	}
 
-	// Check for EIP-897 proxy
-	if containsEIP897Pattern(source) {
-		info.IsProxy = true
-		info.ProxyType = "EIP897"
-		// Implementation would need to call implementation() function
+	info, err := c.detectProxy(context.Background(), state, common.HexToAddress(address))
+	if err != nil {
+		return nil, err
 	}
 
 	// Cache the result
@@ -281,38 +362,22 @@ func (c *Contracts) GetProxyInfo(address string) (*ProxyInfo, error) {
 	return info, nil
 }
 
-// EncodeFunctionCall encodes a function call into calldata
-func (c *Contracts) EncodeFunctionCall(address string, call FunctionCall) (string, error) {
+// EncodeFunctionCall encodes a function call into calldata using the contract's
+// proxy-resolved ABI. Overloads are resolved by argument count and types.
+func (c *Contracts) EncodeFunctionCall(address string, call FunctionCall) (*EncodedCall, error) {
 	if address == "" {
-		return "", errors.New("address cannot be empty")
+		return nil, errors.New("address cannot be empty")
 	}
 	if call.Name == "" {
-		return "", errors.New("function name cannot be empty")
+		return nil, errors.New("function name cannot be empty")
 	}
 
-	// Get function signature
-	signature, err := c.GetFunctionSignature(address, call.Name, true)
+	contractABI, err := c.GetABI(address, true)
 	if err != nil {
-		return "", fmt.Errorf("failed to get function signature: %w", err)
+		return nil, fmt.Errorf("failed to get ABI: %w", err)
 	}
 
-	// Implementation would need to:
-	// 1. Calculate function selector (first 4 bytes of keccak256 of signature)
-	// 2. Encode parameters according to their types
-	// 3. Concatenate selector and encoded parameters
-
-	return "", errors.New("not implemented")
-}
-
-// Helper functions for proxy detection
-func containsEIP1967Pattern(source string) bool {
-	// Implementation would check for EIP-1967 storage slots or patterns
-	return false
-}
-
-func containsEIP897Pattern(source string) bool {
-	// Implementation would check for EIP-897 implementation() function
-	return false
+	return contractABI.EncodeFunctionCall(call)
 }
 
 // GetImplementationAddress returns the implementation address for a proxy contract
@@ -326,6 +391,10 @@ func (c *Contracts) GetImplementationAddress(address string) (string, error) {
 		return "", fmt.Errorf("address %s is not a proxy contract", address)
 	}
 
+	if info.ProxyType == "EIP2535" {
+		return "", fmt.Errorf("diamond %s has no single implementation, use its facets", address)
+	}
+
 	if info.Implementation == "" {
 		return "", fmt.Errorf("implementation address not found for proxy %s", address)
 	}
@@ -0,0 +1,349 @@
+package ethereal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// convertValue converts a Go-native or string value to the Go type go-ethereum
+// uses for the ABI type t, so it can be packed or compared with decoded values.
+// Arrays and tuples may also be given as JSON, and tuples as maps or structs.
+func convertValue(t abi.Type, value interface{}) (interface{}, error) {
+	switch t.T {
+	case abi.SliceTy, abi.ArrayTy:
+		return convertArray(t, value)
+	case abi.TupleTy:
+		return convertTuple(t, value)
+	case abi.AddressTy:
+		return convertAddress(value)
+	case abi.IntTy, abi.UintTy:
+		return convertInteger(t, value)
+	case abi.BoolTy:
+		return convertBool(value)
+	case abi.StringTy:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case abi.BytesTy:
+		return convertBytes(value)
+	case abi.FixedBytesTy, abi.FunctionTy:
+		return convertFixedBytes(t, value)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t.String())
+	}
+	return nil, fmt.Errorf("cannot convert %T to %s", value, t.String())
+}
+
+func convertArray(t abi.Type, value interface{}) (interface{}, error) {
+	value, err := decodeJSONValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot convert %T to %s", value, t.String())
+	}
+
+	var array reflect.Value
+	if t.T == abi.ArrayTy {
+		if rv.Len() != t.Size {
+			return nil, fmt.Errorf("expected %d elements for %s, got %d", t.Size, t.String(), rv.Len())
+		}
+		array = reflect.New(t.GetType()).Elem()
+	} else {
+		array = reflect.MakeSlice(t.GetType(), rv.Len(), rv.Len())
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		elem, err := convertValue(*t.Elem, rv.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		array.Index(i).Set(reflect.ValueOf(elem))
+	}
+	return array.Interface(), nil
+}
+
+func convertTuple(t abi.Type, value interface{}) (interface{}, error) {
+	value, err := decodeJSONValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Components are looked up by their ABI name, or by position
+	var component func(i int) (interface{}, bool)
+	rv := reflect.ValueOf(value)
+	switch {
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		component = func(i int) (interface{}, bool) {
+			v := rv.MapIndex(reflect.ValueOf(t.TupleRawNames[i]).Convert(rv.Type().Key()))
+			if !v.IsValid() {
+				return nil, false
+			}
+			return v.Interface(), true
+		}
+	case rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array:
+		if rv.Len() != len(t.TupleElems) {
+			return nil, fmt.Errorf("expected %d components for %s, got %d", len(t.TupleElems), t.String(), rv.Len())
+		}
+		component = func(i int) (interface{}, bool) {
+			return rv.Index(i).Interface(), true
+		}
+	case rv.Kind() == reflect.Struct:
+		component = func(i int) (interface{}, bool) {
+			name := t.TupleRawNames[i]
+			field := rv.FieldByNameFunc(func(field string) bool {
+				return strings.EqualFold(field, name) || field == abi.ToCamelCase(name)
+			})
+			if !field.IsValid() || !field.CanInterface() {
+				return nil, false
+			}
+			return field.Interface(), true
+		}
+	default:
+		return nil, fmt.Errorf("cannot convert %T to %s", value, t.String())
+	}
+
+	tuple := reflect.New(t.TupleType).Elem()
+	for i, elem := range t.TupleElems {
+		name := t.TupleRawNames[i]
+		v, ok := component(i)
+		if !ok {
+			return nil, fmt.Errorf("missing component %s of %s", name, t.String())
+		}
+		converted, err := convertValue(*elem, v)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %w", name, err)
+		}
+		tuple.Field(i).Set(reflect.ValueOf(converted))
+	}
+	return tuple.Interface(), nil
+}
+
+// decodeJSONValue parses arrays and tuples given as JSON strings
+func decodeJSONValue(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("invalid JSON value %q: %w", s, err)
+	}
+	return decoded, nil
+}
+
+func convertAddress(value interface{}) (common.Address, error) {
+	switch v := value.(type) {
+	case common.Address:
+		return v, nil
+	case *common.Address:
+		if v != nil {
+			return *v, nil
+		}
+	case string:
+		if common.IsHexAddress(v) {
+			return common.HexToAddress(v), nil
+		}
+		return common.Address{}, fmt.Errorf("invalid address %q", v)
+	case []byte:
+		if len(v) == common.AddressLength {
+			return common.BytesToAddress(v), nil
+		}
+	}
+	return common.Address{}, fmt.Errorf("cannot convert %T to address", value)
+}
+
+func convertInteger(t abi.Type, value interface{}) (interface{}, error) {
+	n, err := toBigInt(value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %v to %s: %w", value, t.String(), err)
+	}
+
+	var min, max *big.Int
+	if t.T == abi.UintTy {
+		min = big.NewInt(0)
+		max = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(t.Size)), big.NewInt(1))
+	} else {
+		max = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1)), big.NewInt(1))
+		min = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1)))
+	}
+	if n.Cmp(min) < 0 || n.Cmp(max) > 0 {
+		return nil, fmt.Errorf("value %s out of range for %s", n, t.String())
+	}
+
+	// go-ethereum uses native integers up to 64 bits and *big.Int beyond
+	target := t.GetType()
+	switch target.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(n.Int64()).Convert(target).Interface(), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.ValueOf(n.Uint64()).Convert(target).Interface(), nil
+	default:
+		return n, nil
+	}
+}
+
+func toBigInt(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		if v == nil {
+			return nil, fmt.Errorf("nil integer")
+		}
+		return new(big.Int).Set(v), nil
+	case big.Int:
+		return new(big.Int).Set(&v), nil
+	case int:
+		return big.NewInt(int64(v)), nil
+	case int8:
+		return big.NewInt(int64(v)), nil
+	case int16:
+		return big.NewInt(int64(v)), nil
+	case int32:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case uint:
+		return new(big.Int).SetUint64(uint64(v)), nil
+	case uint8:
+		return new(big.Int).SetUint64(uint64(v)), nil
+	case uint16:
+		return new(big.Int).SetUint64(uint64(v)), nil
+	case uint32:
+		return new(big.Int).SetUint64(uint64(v)), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case float64:
+		// JSON numbers decode to float64; only integral values are meaningful
+		f := new(big.Float).SetFloat64(v)
+		if !f.IsInt() {
+			return nil, fmt.Errorf("%v is not an integer", v)
+		}
+		n, _ := f.Int(nil)
+		return n, nil
+	case json.Number:
+		return toBigInt(v.String())
+	case string:
+		n, ok := new(big.Int).SetString(strings.TrimSpace(v), 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", v)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("unsupported integer type %T", value)
+}
+
+func convertBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("cannot convert %v to bool", value)
+}
+
+func convertBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		if !strings.HasPrefix(v, "0x") && !strings.HasPrefix(v, "0X") {
+			return nil, fmt.Errorf("bytes must be 0x-prefixed hex, got %q", v)
+		}
+		return hexToBytes(v)
+	}
+
+	// Any byte array, e.g. common.Hash
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return b, nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to bytes", value)
+}
+
+func convertFixedBytes(t abi.Type, value interface{}) (interface{}, error) {
+	b, err := convertBytes(value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %v to %s: %w", value, t.String(), err)
+	}
+	if len(b) != t.Size {
+		return nil, fmt.Errorf("expected %d bytes for %s, got %d", t.Size, t.String(), len(b))
+	}
+
+	array := reflect.New(t.GetType()).Elem()
+	reflect.Copy(array, reflect.ValueOf(b))
+	return array.Interface(), nil
+}
+
+func hexToBytes(s string) ([]byte, error) {
+	s = s[2:]
+	if len(s)%2 == 1 {
+		return nil, fmt.Errorf("odd length hex string 0x%s", s)
+	}
+	b := common.FromHex(s)
+	if len(b) != len(s)/2 {
+		return nil, fmt.Errorf("invalid hex string 0x%s", s)
+	}
+	return b, nil
+}
+
+var bigIntType = reflect.TypeOf((*big.Int)(nil))
+
+// valuesEqual compares a converted value with a decoded one, comparing big integers by value
+func valuesEqual(a interface{}, b interface{}) bool {
+	return reflectValuesEqual(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func reflectValuesEqual(x reflect.Value, y reflect.Value) bool {
+	if !x.IsValid() || !y.IsValid() {
+		return x.IsValid() == y.IsValid()
+	}
+	if x.Type() != y.Type() {
+		return false
+	}
+
+	switch {
+	case x.Type() == bigIntType:
+		if x.IsNil() || y.IsNil() {
+			return x.IsNil() == y.IsNil()
+		}
+		return x.Interface().(*big.Int).Cmp(y.Interface().(*big.Int)) == 0
+	case x.Kind() == reflect.Slice || x.Kind() == reflect.Array:
+		if x.Len() != y.Len() {
+			return false
+		}
+		for i := 0; i < x.Len(); i++ {
+			if !reflectValuesEqual(x.Index(i), y.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case x.Kind() == reflect.Struct:
+		for i := 0; i < x.NumField(); i++ {
+			if !reflectValuesEqual(x.Field(i), y.Field(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(x.Interface(), y.Interface())
+	}
+}
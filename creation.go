@@ -0,0 +1,164 @@
+package ethereal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// maxCreationLookupAddresses is the number of addresses getcontractcreation accepts per call
+const maxCreationLookupAddresses = 5
+
+// CreationClient interface defines the explorer method that finds contract creations
+type CreationClient interface {
+	GetContractCreation(addresses []string) ([]ContractCreation, error)
+}
+
+// ContractCreation identifies who deployed a contract and in which transaction
+type ContractCreation struct {
+	ContractAddress string `json:"contractAddress"`
+	ContractCreator string `json:"contractCreator"`
+	TxHash          string `json:"txHash"`
+}
+
+// GetContractCreation returns the creator and creation transaction for each address.
+// Results are returned in the order of addresses; addresses with no creation record
+// (e.g. externally owned accounts) are omitted.
+func (e *Etherscan) GetContractCreation(addresses []string) ([]ContractCreation, error) {
+	found := make(map[string]ContractCreation, len(addresses))
+	var missing []string
+
+	for _, address := range addresses {
+		if address == "" {
+			return nil, errors.New("address cannot be empty")
+		}
+		key := strings.ToLower(address)
+		if _, ok := found[key]; ok || containsString(missing, key) {
+			continue
+		}
+		if cached, err := e.cache.Get(creationCacheKey(key)); err == nil {
+			found[key] = cached.(ContractCreation)
+			continue
+		}
+		missing = append(missing, key)
+	}
+
+	for start := 0; start < len(missing); start += maxCreationLookupAddresses {
+		end := start + maxCreationLookupAddresses
+		if end > len(missing) {
+			end = len(missing)
+		}
+
+		creations, err := e.fetchContractCreation(missing[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, creation := range creations {
+			key := strings.ToLower(creation.ContractAddress)
+			found[key] = creation
+			// Deployment data never changes, so it is safe to keep forever
+			e.cache.SetPermanent(creationCacheKey(key), creation)
+		}
+	}
+
+	result := make([]ContractCreation, 0, len(found))
+	seen := make(map[string]bool, len(found))
+	for _, address := range addresses {
+		key := strings.ToLower(address)
+		if creation, ok := found[key]; ok && !seen[key] {
+			result = append(result, creation)
+			seen[key] = true
+		}
+	}
+
+	return result, nil
+}
+
+func (e *Etherscan) fetchContractCreation(addresses []string) ([]ContractCreation, error) {
+	params := map[string]string{
+		"module":            "contract",
+		"action":            "getcontractcreation",
+		"contractaddresses": strings.Join(addresses, ","),
+	}
+
+	result, err := e.get(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Status != "1" {
+		// Etherscan reports a batch where no address is a contract as an error
+		if message, ok := result.Result.(string); ok && strings.Contains(strings.ToLower(message), "no data found") {
+			return nil, nil
+		}
+		return nil, &EtherscanError{Message: result.Message}
+	}
+
+	var creations []ContractCreation
+	if err := decodeResult(result.Result, &creations); err != nil {
+		return nil, fmt.Errorf("failed to parse contract creation: %w", err)
+	}
+
+	return creations, nil
+}
+
+// DecodeConstructorArguments extracts constructor arguments from the input of a
+// creation transaction, which is the init code followed by the ABI-encoded arguments.
+func DecodeConstructorArguments(contractABI abi.ABI, input []byte) (map[string]interface{}, error) {
+	args := contractABI.Constructor.Inputs
+	if len(args) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	// The init code length is unknown, so try each 32-byte aligned tail starting
+	// from the smallest possible encoding (one head word per argument) and keep
+	// the first one that re-encodes to exactly the same bytes.
+	for size := 32 * len(args); size <= len(input); size += 32 {
+		tail := input[len(input)-size:]
+
+		values, err := args.Unpack(tail)
+		if err != nil {
+			continue
+		}
+		encoded, err := args.Pack(values...)
+		if err != nil || !bytes.Equal(encoded, tail) {
+			continue
+		}
+
+		decoded := make(map[string]interface{}, len(args))
+		if err := args.UnpackIntoMap(decoded, tail); err != nil {
+			return nil, fmt.Errorf("failed to decode constructor arguments: %w", err)
+		}
+		return decoded, nil
+	}
+
+	return nil, errors.New("constructor arguments not found in creation transaction input")
+}
+
+// DecodeCreationTransaction extracts constructor arguments from a creation transaction.
+// Contracts deployed by a factory or a CREATE2 deployer were created by a call whose
+// input is not the init code, and their arguments cannot be recovered from it.
+func DecodeCreationTransaction(contractABI abi.ABI, tx *types.Transaction) (map[string]interface{}, error) {
+	if tx.To() != nil {
+		return nil, fmt.Errorf("contract was created by %s in transaction %s, whose input is a call rather than init code; constructor arguments cannot be decoded from it", tx.To().Hex(), tx.Hash().Hex())
+	}
+	return DecodeConstructorArguments(contractABI, tx.Data())
+}
+
+func creationCacheKey(address string) string {
+	return fmt.Sprintf("etherscan:creation:%s", address)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package ethereal
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TransactionReader interface defines the methods required to look up transactions
+type TransactionReader interface {
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+}
+
+// DecodedCall is a decoded function call. Inputs hold go-ethereum's Go values, so the
+// embedded FunctionCall can be passed back to EncodeFunctionCall.
+type DecodedCall struct {
+	FunctionCall
+	Target    string // the called contract
+	Signature string
+	Selector  string // 0x-prefixed 4-byte selector
+	// Calls are the calls found in bytes arguments, as in multicall, Multicall3,
+	// Safe execTransaction and multiSend, or execute(address,bytes) patterns
+	Calls []DecodedCall
+}
+
+// Args returns the inputs by name
+func (d *DecodedCall) Args() map[string]interface{} {
+	args := make(map[string]interface{}, len(d.Inputs))
+	for _, input := range d.Inputs {
+		args[input.Name] = input.Value
+	}
+	return args
+}
+
+// DecodeFunctionCall decodes calldata of one of the ABI's functions
+func (c *ContractABI) DecodeFunctionCall(data []byte) (*DecodedCall, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("calldata of %d bytes has no function selector", len(data))
+	}
+
+	method, err := c.MethodById(data[:4])
+	if err != nil {
+		return nil, fmt.Errorf("unknown function selector %s", hexutil.Encode(data[:4]))
+	}
+
+	values, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode arguments of %s: %w", method.Sig, err)
+	}
+
+	inputs := namedArguments(method.Inputs)
+	params := make([]FunctionParam, len(inputs))
+	for i, input := range inputs {
+		params[i] = FunctionParam{Name: input.Name, Type: input.Type.String(), Value: values[i]}
+	}
+
+	return &DecodedCall{
+		FunctionCall: FunctionCall{Name: method.RawName, Inputs: params},
+		Signature:    method.Sig,
+		Selector:     hexutil.Encode(method.ID),
+	}, nil
+}
+
+// DecodeCalldata decodes calldata sent to a contract using its proxy-resolved ABI,
+// along with the calls nested in its arguments
+func (c *Contracts) DecodeCalldata(address string, input string) (*DecodedCall, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid address %s", address)
+	}
+	data, err := hexutil.Decode(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calldata: %w", err)
+	}
+	return c.decodeCall(common.HexToAddress(address), data)
+}
+
+// DecodeTransaction decodes the input of a transaction
+func (c *Contracts) DecodeTransaction(txHash string) (*DecodedCall, error) {
+	reader, ok := c.client.(TransactionReader)
+	if !ok {
+		return nil, errNoRPCClient
+	}
+
+	tx, _, err := reader.TransactionByHash(context.Background(), common.HexToHash(txHash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", txHash, err)
+	}
+	if tx.To() == nil {
+		return nil, fmt.Errorf("transaction %s creates a contract", txHash)
+	}
+
+	return c.decodeCall(*tx.To(), tx.Data())
+}
+
+func (c *Contracts) decodeCall(target common.Address, data []byte) (*DecodedCall, error) {
+	contractABI, err := c.GetABI(target.Hex(), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ABI: %w", err)
+	}
+
+	call, err := contractABI.DecodeFunctionCall(data)
+	if err != nil {
+		return nil, err
+	}
+	call.Target = target.Hex()
+
+	if call.Name == "multiSend" && len(call.Inputs) == 1 {
+		if packed, ok := call.Inputs[0].Value.([]byte); ok {
+			call.Calls = c.multiSendCalls(packed)
+			return call, nil
+		}
+	}
+
+	method, _ := contractABI.MethodById(data[:4])
+	argTypes := make([]abi.Type, len(method.Inputs))
+	values := make([]interface{}, len(call.Inputs))
+	for i, input := range method.Inputs {
+		argTypes[i] = input.Type
+		values[i] = call.Inputs[i].Value
+	}
+	call.Calls = c.nestedCalls(target, argTypes, values)
+
+	return call, nil
+}
+
+// nestedCalls decodes bytes values that hold calldata. The call goes to the closest
+// address before the bytes, as in Multicall3, Safe or execute(address,uint256,bytes),
+// with bytes[] paired with a preceding address[] as in TimelockController batches,
+// and to the contract itself otherwise, as in multicall(bytes[]). Bytes that are not
+// calldata for a function of the target's ABI are ignored.
+func (c *Contracts) nestedCalls(self common.Address, argTypes []abi.Type, values []interface{}) []DecodedCall {
+	target := self
+	var targets []common.Address
+
+	var calls []DecodedCall
+	for i, t := range argTypes {
+		switch {
+		case t.T == abi.AddressTy:
+			target = values[i].(common.Address)
+		case (t.T == abi.SliceTy || t.T == abi.ArrayTy) && t.Elem.T == abi.AddressTy:
+			rv := reflect.ValueOf(values[i])
+			targets = make([]common.Address, rv.Len())
+			for j := range targets {
+				targets[j] = rv.Index(j).Interface().(common.Address)
+			}
+		case t.T == abi.BytesTy:
+			calls = c.appendNestedCall(calls, target, values[i].([]byte))
+		case (t.T == abi.SliceTy || t.T == abi.ArrayTy) && t.Elem.T == abi.BytesTy:
+			rv := reflect.ValueOf(values[i])
+			for j := 0; j < rv.Len(); j++ {
+				callTarget := target
+				if len(targets) == rv.Len() {
+					callTarget = targets[j]
+				}
+				calls = c.appendNestedCall(calls, callTarget, rv.Index(j).Bytes())
+			}
+		case t.T == abi.TupleTy:
+			calls = append(calls, c.nestedCalls(self, tupleElems(t), tupleValues(values[i]))...)
+		case (t.T == abi.SliceTy || t.T == abi.ArrayTy) && t.Elem.T == abi.TupleTy:
+			rv := reflect.ValueOf(values[i])
+			for j := 0; j < rv.Len(); j++ {
+				calls = append(calls, c.nestedCalls(self, tupleElems(*t.Elem), tupleValues(rv.Index(j).Interface()))...)
+			}
+		}
+	}
+	return calls
+}
+
+func (c *Contracts) appendNestedCall(calls []DecodedCall, target common.Address, data []byte) []DecodedCall {
+	if len(data) < 4 {
+		return calls
+	}
+	call, err := c.decodeCall(target, data)
+	if err != nil {
+		return calls
+	}
+	return append(calls, *call)
+}
+
+// multiSendCalls decodes Safe's MultiSend packing: operation (1 byte), to (20 bytes),
+// value (32 bytes), data length (32 bytes) and data, repeated
+func (c *Contracts) multiSendCalls(packed []byte) []DecodedCall {
+	var calls []DecodedCall
+	for len(packed) >= 85 {
+		to := common.BytesToAddress(packed[1:21])
+		length := new(big.Int).SetBytes(packed[53:85])
+		if !length.IsUint64() || length.Uint64() > uint64(len(packed)-85) {
+			break
+		}
+		size := int(length.Uint64())
+		calls = c.appendNestedCall(calls, to, packed[85:85+size])
+		packed = packed[85+size:]
+	}
+	return calls
+}
+
+func tupleElems(t abi.Type) []abi.Type {
+	elems := make([]abi.Type, len(t.TupleElems))
+	for i, elem := range t.TupleElems {
+		elems[i] = *elem
+	}
+	return elems
+}
+
+func tupleValues(tuple interface{}) []interface{} {
+	rv := reflect.ValueOf(tuple)
+	values := make([]interface{}, rv.NumField())
+	for i := range values {
+		values[i] = rv.Field(i).Interface()
+	}
+	return values
+}
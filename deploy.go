@@ -0,0 +1,235 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// deterministicDeployer is the deterministic deployment proxy, deployed at the same
+// address on most chains with a presigned transaction. It deploys its calldata after
+// a 32-byte salt with CREATE2.
+var deterministicDeployer = common.HexToAddress("0x4e59b44847b379578588920cA78FbF26c0B4956C")
+
+// Library placeholders of solc take the 40 characters of an address: __$<34 hex digits of
+// the keccak256 of the fully qualified name>$__ since 0.5, and the name padded with
+// underscores before. Bytecode has no underscores otherwise.
+var placeholderPattern = regexp.MustCompile(`__.{36}__`)
+
+// LinkReference is the byte offset and length of a library address in bytecode
+type LinkReference struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+// DeployOpts configures a deployment
+type DeployOpts struct {
+	TransactOpts
+	// Libraries maps library names or source:name to addresses. Without link references
+	// only source:name matches the placeholders of solc 0.5 and later.
+	Libraries map[string]string
+	// Salt deploys with CREATE2 through the deterministic deployment proxy when set
+	Salt *common.Hash
+}
+
+// Deployment is a sent deployment with the address the contract is created at
+type Deployment struct {
+	*PendingTransaction
+	Address common.Address
+}
+
+// Link returns the bytecode with library placeholders replaced by the given addresses.
+// Libraries are matched by name or source:name, through LinkReferences when present
+// and otherwise by the placeholders solc emits. Unlinked libraries are an error.
+func (a *Artifact) Link(libraries map[string]string) (string, error) {
+	code := strings.TrimPrefix(a.Bytecode, "0x")
+	if code == "" {
+		return "", fmt.Errorf("artifact %s has no bytecode; abstract contracts and interfaces cannot be deployed", a.ContractName)
+	}
+	for name, address := range libraries {
+		if !common.IsHexAddress(address) {
+			return "", fmt.Errorf("invalid address %s of library %s", address, name)
+		}
+	}
+
+	for source, libs := range a.LinkReferences {
+		for lib, refs := range libs {
+			address, ok := libraryAddress(libraries, source, lib)
+			if !ok {
+				return "", fmt.Errorf("library %s:%s is not linked", source, lib)
+			}
+			for _, ref := range refs {
+				if ref.Length != common.AddressLength || 2*(ref.Start+ref.Length) > len(code) {
+					return "", fmt.Errorf("invalid link reference to %s:%s at %d", source, lib, ref.Start)
+				}
+				code = code[:2*ref.Start] + address + code[2*(ref.Start+ref.Length):]
+			}
+		}
+	}
+
+	for name, address := range libraries {
+		for _, placeholder := range libraryPlaceholders(name) {
+			code = strings.ReplaceAll(code, placeholder, strings.TrimPrefix(strings.ToLower(address), "0x"))
+		}
+	}
+
+	if unlinked := placeholderPattern.FindAllString(code, -1); len(unlinked) > 0 {
+		return "", fmt.Errorf("bytecode has unlinked libraries: %s", strings.Join(uniqueSorted(unlinked), ", "))
+	}
+	if _, err := hexutil.Decode("0x" + code); err != nil {
+		return "", fmt.Errorf("invalid bytecode: %w", err)
+	}
+	return "0x" + code, nil
+}
+
+// DeployData returns the init code of a deployment: the linked bytecode followed by
+// the ABI-encoded constructor arguments, converted as by EncodeFunctionCall
+func (a *Artifact) DeployData(args []interface{}, libraries map[string]string) ([]byte, error) {
+	contractABI, err := ParseABI(a.ABI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact ABI: %w", err)
+	}
+	return a.deployData(contractABI, args, libraries)
+}
+
+func (a *Artifact) deployData(contractABI *ContractABI, args []interface{}, libraries map[string]string) ([]byte, error) {
+	linked, err := a.Link(libraries)
+	if err != nil {
+		return nil, err
+	}
+	inputs := contractABI.Constructor.Inputs
+	if len(args) != len(inputs) {
+		return nil, fmt.Errorf("constructor takes %d arguments, got %d", len(inputs), len(args))
+	}
+	converted, err := convertArguments(inputs, args, nil)
+	if err != nil {
+		return nil, fmt.Errorf("constructor %w", err)
+	}
+	encoded, err := inputs.Pack(converted...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode constructor arguments: %w", err)
+	}
+	return append(common.FromHex(linked), encoded...), nil
+}
+
+// PredictCreate2Address returns the address the deterministic deployment proxy
+// deploys init code at with a salt
+func PredictCreate2Address(salt common.Hash, initCode []byte) common.Address {
+	return crypto.CreateAddress2(deterministicDeployer, salt, crypto.Keccak256(initCode))
+}
+
+// Deploy sends a transaction deploying an artifact. With opts.Salt the contract is
+// deployed with CREATE2 through the deterministic deployment proxy, at an address that
+// only depends on the salt and init code; otherwise with CREATE at an address derived
+// from the account and nonce. The address is known before the transaction is mined.
+func (c *Contracts) Deploy(account *Account, artifact *Artifact, args []interface{}, opts DeployOpts) (*Deployment, error) {
+	if artifact == nil {
+		return nil, errors.New("artifact cannot be nil")
+	}
+	contractABI, err := ParseABI(artifact.ABI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact ABI: %w", err)
+	}
+	if opts.Value != nil && opts.Value.Sign() > 0 && !contractABI.Constructor.IsPayable() {
+		return nil, errors.New("constructor is not payable")
+	}
+	initCode, err := artifact.deployData(contractABI, args, opts.Libraries)
+	if err != nil {
+		return nil, err
+	}
+
+	req := transactionRequest{
+		value:                opts.Value,
+		data:                 initCode,
+		gas:                  opts.GasLimit,
+		nonce:                opts.Nonce,
+		maxFeePerGas:         opts.MaxFeePerGas,
+		maxPriorityFeePerGas: opts.MaxPriorityFeePerGas,
+		abis:                 []*ContractABI{contractABI},
+	}
+
+	var address common.Address
+	if opts.Salt != nil {
+		address = PredictCreate2Address(*opts.Salt, initCode)
+		if err := c.checkCreate2(address); err != nil {
+			return nil, err
+		}
+		deployer := deterministicDeployer
+		req.to = &deployer
+		req.data = append(opts.Salt.Bytes(), initCode...)
+	}
+
+	pending, err := c.sendTransaction(account, req, opts.PollInterval)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Salt == nil {
+		address = crypto.CreateAddress(pending.From, pending.Tx.Nonce())
+	}
+	return &Deployment{PendingTransaction: pending, Address: address}, nil
+}
+
+// checkCreate2 checks that the deployment proxy exists and the address is still free,
+// as the proxy reverts without reason otherwise
+func (c *Contracts) checkCreate2(address common.Address) error {
+	state, ok := c.client.(StateClient)
+	if !ok {
+		return errNoRPCClient
+	}
+	ctx := context.Background()
+	code, err := state.CodeAt(ctx, deterministicDeployer, nil)
+	if err != nil {
+		return fmt.Errorf("failed to check for the deployment proxy: %w", err)
+	}
+	if len(code) == 0 {
+		return fmt.Errorf("deterministic deployment proxy %s is not deployed on this chain", deterministicDeployer.Hex())
+	}
+	code, err = state.CodeAt(ctx, address, nil)
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", address.Hex(), err)
+	}
+	if len(code) > 0 {
+		return fmt.Errorf("a contract is already deployed at %s with this salt and init code", address.Hex())
+	}
+	return nil
+}
+
+// libraryAddress finds the address of a library by source:name or name, without 0x
+func libraryAddress(libraries map[string]string, source string, name string) (string, bool) {
+	address, ok := libraries[source+":"+name]
+	if !ok {
+		address, ok = libraries[name]
+	}
+	return strings.TrimPrefix(strings.ToLower(address), "0x"), ok
+}
+
+// libraryPlaceholders returns the placeholders solc may have emitted for a library
+func libraryPlaceholders(name string) []string {
+	hash := hexutil.Encode(crypto.Keccak256([]byte(name)))[2:36]
+	legacy := name
+	if len(legacy) > 36 {
+		legacy = legacy[:36]
+	}
+	legacy = "__" + legacy + strings.Repeat("_", 38-len(legacy))
+	return []string{"__$" + hash + "$__", legacy}
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
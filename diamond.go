@@ -0,0 +1,185 @@
+package ethereal
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// diamondLoupeABI is the part of EIP-2535's IDiamondLoupe used to enumerate facets
+const diamondLoupeABI = `[{"type":"function","name":"facets","stateMutability":"view","inputs":[],
+	"outputs":[{"name":"facets_","type":"tuple[]","components":[
+		{"name":"facetAddress","type":"address"},{"name":"functionSelectors","type":"bytes4[]"}]}]}]`
+
+// diamondCutTopic is the topic of DiamondCut((address,uint8,bytes4[])[],address,bytes),
+// emitted whenever facets are added, replaced or removed
+var diamondCutTopic = common.HexToHash("0x8faa70878671ccd212d20771b795c50af8fd3ff6cf27f4bde57e5d4de0aeb673")
+
+var diamondLoupe = mustParseABI(diamondLoupeABI)
+
+// Facet is a facet of an EIP-2535 diamond with the function selectors routed to it
+type Facet struct {
+	Address   string
+	Selectors []string // 0x-prefixed 4-byte selectors
+}
+
+func mustParseABI(raw string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(raw))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// readFacets calls facets() on a contract, reporting false for contracts that are not diamonds
+func readFacets(ctx context.Context, state StateClient, address common.Address, blockNumber *big.Int) ([]Facet, bool, error) {
+	method := diamondLoupe.Methods["facets"]
+	result, err := state.CallContract(ctx, ethereum.CallMsg{To: &address, Data: method.ID}, blockNumber)
+	if err != nil {
+		return nil, false, nil
+	}
+	values, err := method.Outputs.Unpack(result)
+	if err != nil || len(values) != 1 {
+		return nil, false, nil
+	}
+	// facets() returns (address facetAddress, bytes4[] functionSelectors)[]
+	rv := reflect.ValueOf(values[0])
+	if rv.Kind() != reflect.Slice || rv.Len() == 0 {
+		return nil, false, nil
+	}
+
+	facets := make([]Facet, rv.Len())
+	for i := range facets {
+		fields := tupleValues(rv.Index(i).Interface())
+		facets[i].Address = fields[0].(common.Address).Hex()
+		for _, selector := range fields[1].([][4]byte) {
+			facets[i].Selectors = append(facets[i].Selectors, hexutil.Encode(selector[:]))
+		}
+	}
+	return facets, true, nil
+}
+
+// detectDiamond reads the facets of a diamond at the latest block and remembers that
+// block, so that refreshDiamond only looks for DiamondCut events after it
+func (c *Contracts) detectDiamond(ctx context.Context, state StateClient, address common.Address) ([]Facet, bool, error) {
+	header, err := c.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	facets, ok, err := readFacets(ctx, state, address, header.Number)
+	if !ok || err != nil {
+		return nil, false, err
+	}
+
+	if err := c.cache.Set(diamondBlockKey(address), header.Number.Uint64()); err != nil {
+		return nil, false, fmt.Errorf("failed to cache diamond block: %w", err)
+	}
+	return facets, true, nil
+}
+
+func diamondBlockKey(address common.Address) string {
+	return fmt.Sprintf("diamond_block_%s", address.Hex())
+}
+
+// refreshDiamond detects the facets of a known diamond again when DiamondCut was emitted
+// since they were read, and reports whether they changed. The diamond may be the
+// contract itself or the end of its proxy chain.
+func (c *Contracts) refreshDiamond(address string) (bool, error) {
+	cachedInfo, err := c.cache.Get(fmt.Sprintf("proxy_info_%s", address))
+	if err != nil {
+		// Not detected yet
+		return false, nil
+	}
+	info := cachedInfo.(*ProxyInfo)
+	if len(info.Facets) == 0 {
+		return false, nil
+	}
+	diamond := common.HexToAddress(address)
+	if info.ProxyType != "EIP2535" {
+		diamond = common.HexToAddress(info.Implementation)
+	}
+
+	cached, err := c.cache.Get(diamondBlockKey(diamond))
+	if err != nil {
+		return false, nil
+	}
+	checked := cached.(uint64)
+
+	ctx := context.Background()
+	header, err := c.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	head := header.Number.Uint64()
+	if head <= checked {
+		return false, nil
+	}
+
+	logs, err := c.fetchLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(checked + 1),
+		ToBlock:   new(big.Int).SetUint64(head),
+		Addresses: []common.Address{diamond},
+		Topics:    [][]common.Hash{{diamondCutTopic}},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check for DiamondCut events: %w", err)
+	}
+	if len(logs) == 0 {
+		if err := c.cache.Set(diamondBlockKey(diamond), head); err != nil {
+			return false, fmt.Errorf("failed to cache diamond block: %w", err)
+		}
+		return false, nil
+	}
+
+	state, ok := c.client.(StateClient)
+	if !ok {
+		return false, errNoRPCClient
+	}
+	refreshed, err := c.detectProxy(ctx, state, common.HexToAddress(address))
+	if err != nil {
+		return false, err
+	}
+	if err := c.cache.Set(fmt.Sprintf("proxy_info_%s", address), refreshed); err != nil {
+		return false, fmt.Errorf("failed to cache proxy info: %w", err)
+	}
+	return true, nil
+}
+
+// diamondABI combines the ABIs of a diamond's facets, keeping the functions each facet
+// is routed for. Facets without a verified ABI are skipped.
+func (c *Contracts) diamondABI(address string, facets []Facet, diamondABI *ContractABI, diamondErr error) (*ContractABI, error) {
+	var abis []*ContractABI
+	for _, facet := range facets {
+		facetABI, err := c.GetABI(facet.Address, false)
+		if err != nil {
+			continue
+		}
+
+		selectors := make(map[string]bool, len(facet.Selectors))
+		for _, selector := range facet.Selectors {
+			selectors[selector] = true
+		}
+		routed, err := facetABI.filterFunctions(func(method abi.Method) bool {
+			return selectors[hexutil.Encode(method.ID)]
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter ABI of facet %s: %w", facet.Address, err)
+		}
+		abis = append(abis, routed)
+	}
+
+	if diamondErr == nil {
+		abis = append(abis, diamondABI)
+	}
+	if len(abis) == 0 {
+		return nil, fmt.Errorf("no verified facets found for diamond %s", address)
+	}
+	return MergeABIs(abis...)
+}
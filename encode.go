@@ -0,0 +1,156 @@
+package ethereal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// EncodedCall is the calldata of a function call
+type EncodedCall struct {
+	Signature string // the resolved overload, e.g. transfer(address,uint256)
+	Selector  string // 0x-prefixed 4-byte selector
+	Arguments string // 0x-prefixed ABI-encoded arguments
+	Data      string // selector followed by arguments
+}
+
+// EncodeFunctionCall encodes a call to the function named call.Name, which may also be
+// a full signature to pick an overload. Input values may be Go values of the types
+// go-ethereum uses, or strings and JSON as found in config files and user input.
+// A FunctionParam.Type narrows down overloads when set.
+func (c *ContractABI) EncodeFunctionCall(call FunctionCall) (*EncodedCall, error) {
+	values := make([]interface{}, len(call.Inputs))
+	types := make([]string, len(call.Inputs))
+	for i, input := range call.Inputs {
+		values[i] = input.Value
+		types[i] = input.Type
+	}
+
+	method, args, err := c.resolveMethod(call.Name, values, types)
+	if err != nil {
+		return nil, err
+	}
+
+	packed, err := method.Inputs.Pack(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments of %s: %w", method.Sig, err)
+	}
+
+	return &EncodedCall{
+		Signature: method.Sig,
+		Selector:  hexutil.Encode(method.ID),
+		Arguments: hexutil.Encode(packed),
+		Data:      hexutil.Encode(append(append([]byte{}, method.ID...), packed...)),
+	}, nil
+}
+
+// resolveMethod picks the overload of name whose inputs accept values, and returns
+// the values converted to its input types. Non-empty types must match the input types.
+func (c *ContractABI) resolveMethod(name string, values []interface{}, types []string) (*abi.Method, []interface{}, error) {
+	methods := c.MethodsByName(name)
+	if len(methods) == 0 {
+		return nil, nil, fmt.Errorf("function %s not found in contract ABI", name)
+	}
+
+	var (
+		matches   []abi.Method
+		converted [][]interface{}
+		lastErr   error
+	)
+	for _, method := range methods {
+		if len(method.Inputs) != len(values) {
+			lastErr = fmt.Errorf("%s takes %d arguments, got %d", method.Sig, len(method.Inputs), len(values))
+			continue
+		}
+		args, err := convertArguments(method.Inputs, values, types)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", method.Sig, err)
+			continue
+		}
+		matches = append(matches, method)
+		converted = append(converted, args)
+	}
+
+	switch len(matches) {
+	case 0:
+		if len(methods) == 1 {
+			return nil, nil, lastErr
+		}
+		return nil, nil, fmt.Errorf("no overload of %s accepts the given arguments: %s", name, overloadList(methods))
+	case 1:
+		return &matches[0], converted[0], nil
+	default:
+		return nil, nil, fmt.Errorf("call to %s is ambiguous between %s, use a full signature or argument types", name, overloadList(matches))
+	}
+}
+
+func convertArguments(inputs abi.Arguments, values []interface{}, types []string) ([]interface{}, error) {
+	args := make([]interface{}, len(inputs))
+	for i, input := range inputs {
+		if i < len(types) && types[i] != "" && !typeMatches(types[i], input.Type) {
+			return nil, fmt.Errorf("argument %d is %s, not %s", i, input.Type.String(), types[i])
+		}
+
+		arg, err := convertValue(input.Type, values[i])
+		if err != nil {
+			name := input.Name
+			if name == "" {
+				name = fmt.Sprintf("%d", i)
+			}
+			return nil, fmt.Errorf("argument %s: %w", name, err)
+		}
+		args[i] = arg
+	}
+	return args, nil
+}
+
+// typeMatches compares a Solidity type name with an ABI type, accepting the uint, int
+// and byte aliases and "tuple" for any tuple
+func typeMatches(name string, t abi.Type) bool {
+	name = strings.ReplaceAll(strings.TrimSpace(name), " ", "")
+	expected := t.String()
+	if name == expected {
+		return true
+	}
+
+	base, suffix := splitArraySuffix(name)
+	expectedBase, expectedSuffix := splitArraySuffix(expected)
+	if suffix != expectedSuffix {
+		return false
+	}
+
+	switch base {
+	case "uint":
+		base = "uint256"
+	case "int":
+		base = "int256"
+	case "byte":
+		base = "bytes1"
+	case "tuple":
+		return strings.HasPrefix(expectedBase, "(")
+	}
+	return base == expectedBase
+}
+
+// splitArraySuffix splits "uint256[2][]" into "uint256" and "[2][]"
+func splitArraySuffix(name string) (string, string) {
+	end := len(name)
+	for end > 0 && name[end-1] == ']' {
+		open := strings.LastIndex(name[:end], "[")
+		if open < 0 {
+			break
+		}
+		end = open
+	}
+	return name[:end], name[end:]
+}
+
+func overloadList(methods []abi.Method) string {
+	signatures := make([]string, len(methods))
+	for i, method := range methods {
+		signatures[i] = method.Sig
+	}
+	return strings.Join(signatures, ", ")
+}
@@ -1,7 +1,9 @@
 package ethereal
 
 import (
-	"time"
+	"context"
+	"errors"
+	"fmt"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
@@ -14,45 +16,173 @@ type EtherealFacade struct {
 	web3      *ethclient.Client
 	accounts  *Accounts
 	cache     *Cache
+	providers EtherscanClient
 }
 
 // NewEtherealFacade creates a new instance of EtherealFacade
 func NewEtherealFacade(etherscan *Etherscan, web3 *ethclient.Client, accounts *Accounts, cache *Cache) *EtherealFacade {
-	return &EtherealFacade{
+	facade := &EtherealFacade{
 		etherscan: etherscan,
 		web3:      web3,
 		accounts:  accounts,
 		cache:     cache,
 	}
+	if web3 != nil && accounts != nil {
+		accounts.SetTransactionBuilder(web3, facade.feeOracle())
+	}
+	return facade
+}
+
+// SetABIProvider replaces Etherscan as the ABI and source provider, e.g. with a ChainedProvider
+func (e *EtherealFacade) SetABIProvider(provider EtherscanClient) {
+	e.providers = provider
 }
 
-// GetBlockByTimestamp gets the block number for a given timestamp
+// GetBlockByTimestamp gets the first block at or after a given timestamp
 func (e *EtherealFacade) GetBlockByTimestamp(timestamp int64) (int64, error) {
-	return e.etherscan.GetBlockByTimestamp(timestamp)
+	resolver := e.timestampResolver()
+	if resolver == nil {
+		return 0, errors.New("no RPC client or Etherscan configured")
+	}
+	return resolver.GetBlockByTimestamp(timestamp, "after")
 }
 
 // GetABI gets the ABI for a given address
-func (e *EtherealFacade) GetABI(address string, resolveProxy bool) (map[string]interface{}, error) {
+func (e *EtherealFacade) GetABI(address string, resolveProxy bool) (*ContractABI, error) {
 	contracts := e.contracts()
 	return contracts.GetABI(address, resolveProxy)
 }
 
 // ListEvents gets a list of events for a given address
-func (e *EtherealFacade) ListEvents(address string, resolveProxy bool) ([]string, error) {
+func (e *EtherealFacade) ListEvents(address string, resolveProxy bool) ([]ContractEvent, error) {
 	contracts := e.contracts()
 	return contracts.ListEvents(address, resolveProxy)
 }
 
-// GetContract gets a contract for a given address
+// ListFunctions gets a list of functions for a given address
+func (e *EtherealFacade) ListFunctions(address string, resolveProxy bool) ([]ContractFunction, error) {
+	contracts := e.contracts()
+	return contracts.ListFunctions(address, resolveProxy)
+}
+
+// ListErrors gets a list of custom errors for a given address
+func (e *EtherealFacade) ListErrors(address string, resolveProxy bool) ([]ContractError, error) {
+	contracts := e.contracts()
+	return contracts.ListErrors(address, resolveProxy)
+}
+
+// GetContract gets a contract for a given address, bound to the RPC client
 func (e *EtherealFacade) GetContract(address string, resolveProxy bool) (*bind.BoundContract, error) {
+	if e.web3 == nil {
+		return nil, errNoRPCClient
+	}
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid address %s", address)
+	}
+	contractABI, err := e.GetABI(address, resolveProxy)
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(common.HexToAddress(address), contractABI.ABI, e.web3, e.web3, e.web3), nil
+}
+
+// Call executes a read-only contract call and decodes its outputs
+func (e *EtherealFacade) Call(address string, method string, opts CallOpts, args ...interface{}) (*CallResult, error) {
 	contracts := e.contracts()
-	return contracts.GetContract(address, resolveProxy)
+	return contracts.Call(address, method, opts, args...)
 }
 
-// Account represents a derived Ethereum account
-type Account struct {
-	PublicKey  common.Address
-	PrivateKey string
+// CallBatch executes many read-only contract calls, through Multicall3 where deployed
+func (e *EtherealFacade) CallBatch(calls []CallRequest, opts CallOpts) ([]BatchResult, error) {
+	contracts := e.contracts()
+	return contracts.CallBatch(calls, opts)
+}
+
+// Simulate runs a transaction with state and block overrides without sending it
+func (e *EtherealFacade) Simulate(call SimulationCall, opts SimulateOpts) (*SimulationResult, error) {
+	contracts := e.contracts()
+	return contracts.Simulate(call, opts)
+}
+
+// SimulateBundle runs transactions in order in one simulated block
+func (e *EtherealFacade) SimulateBundle(calls []SimulationCall, opts SimulateOpts) ([]SimulationResult, error) {
+	contracts := e.contracts()
+	return contracts.SimulateBundle(calls, opts)
+}
+
+// Transact sends a transaction calling a state-changing contract function
+func (e *EtherealFacade) Transact(account *Account, address string, method string, args []interface{}, opts TransactOpts) (*PendingTransaction, error) {
+	contracts := e.contracts()
+	return contracts.Transact(account, address, method, args, opts)
+}
+
+// Deploy sends a transaction deploying a compiled contract, with CREATE2 when opts.Salt is set
+func (e *EtherealFacade) Deploy(account *Account, artifact *Artifact, args []interface{}, opts DeployOpts) (*Deployment, error) {
+	contracts := e.contracts()
+	return contracts.Deploy(account, artifact, args, opts)
+}
+
+// DecodeCalldata decodes calldata sent to a contract, including nested calls
+func (e *EtherealFacade) DecodeCalldata(address string, input string) (*DecodedCall, error) {
+	contracts := e.contracts()
+	return contracts.DecodeCalldata(address, input)
+}
+
+// DecodeTransaction decodes the input of a transaction, including nested calls
+func (e *EtherealFacade) DecodeTransaction(txHash string) (*DecodedCall, error) {
+	contracts := e.contracts()
+	return contracts.DecodeTransaction(txHash)
+}
+
+// GetContractCreation gets the creator and creation transaction of a contract
+func (e *EtherealFacade) GetContractCreation(address string) (*ContractCreation, error) {
+	creations, err := e.etherscan.GetContractCreation([]string{address})
+	if err != nil {
+		return nil, err
+	}
+	if len(creations) == 0 {
+		return nil, fmt.Errorf("no creation record found for %s", address)
+	}
+	return &creations[0], nil
+}
+
+// GetContractCreations gets the creators and creation transactions of several contracts
+func (e *EtherealFacade) GetContractCreations(addresses []string) ([]ContractCreation, error) {
+	return e.etherscan.GetContractCreation(addresses)
+}
+
+// GetConstructorArguments decodes the constructor arguments a contract was deployed with
+func (e *EtherealFacade) GetConstructorArguments(address string) (map[string]interface{}, error) {
+	if e.web3 == nil {
+		return nil, errNoRPCClient
+	}
+
+	creation, err := e.GetContractCreation(address)
+	if err != nil {
+		return nil, err
+	}
+
+	contractABI, err := e.GetABI(address, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ABI: %w", err)
+	}
+
+	tx, _, err := e.web3.TransactionByHash(context.Background(), common.HexToHash(creation.TxHash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get creation transaction %s: %w", creation.TxHash, err)
+	}
+
+	return DecodeCreationTransaction(contractABI.ABI, tx)
+}
+
+// SuggestFees gets slow, standard and fast EIP-1559 fee suggestions for the current chain
+func (e *EtherealFacade) SuggestFees() (*FeeSuggestions, error) {
+	return e.feeOracle().SuggestFees()
+}
+
+// SuggestFeesWithWaits gets fee suggestions with Etherscan's estimated confirmation times
+func (e *EtherealFacade) SuggestFeesWithWaits() (*FeeSuggestions, error) {
+	return e.feeOracle().SuggestFeesWithWaits()
 }
 
 // DeriveAccount derives public and private key from a seed phrase
@@ -65,19 +195,72 @@ func (e *EtherealFacade) GenerateSeedPhrase(strength int) (string, error) {
 	return e.accounts.GenerateSeedPhrase(strength)
 }
 
-// EventFilter represents filters for event queries
-type EventFilter struct {
-	FromTime        time.Time
-	ToTime          time.Time
-	ArgumentFilters map[string]interface{}
-}
-
 // GetEvents gets events for a given address
-func (e *EtherealFacade) GetEvents(address string, event string, filter EventFilter, resolveProxy bool) ([]interface{}, error) {
+func (e *EtherealFacade) GetEvents(address string, event string, filter EventFilter, resolveProxy bool) ([]EventRecord, error) {
 	contracts := e.contracts()
 	return contracts.GetEvents(address, event, filter, resolveProxy)
 }
 
+// SubscribeEvents backfills events from filter.FromBlock and then follows new blocks
+func (e *EtherealFacade) SubscribeEvents(address string, event string, filter EventFilter, resolveProxy bool) (*EventSubscription, error) {
+	contracts := e.contracts()
+	return contracts.SubscribeEvents(address, event, filter, resolveProxy)
+}
+
+// WatchTransactions follows transactions until they are confirmed, replaced or dropped
+func (e *EtherealFacade) WatchTransactions(config WatcherConfig, hashes ...string) (*TxWatcher, error) {
+	contracts := e.contracts()
+	contracts.SetWatcherConfig(config)
+	return contracts.WatchTransactions(hashes...)
+}
+
+func (e *EtherealFacade) feeOracle() *FeeOracle {
+	// Avoid wrapping nil pointers in non-nil interfaces
+	var tracker GasTrackerClient
+	if e.etherscan != nil {
+		tracker = e.etherscan
+	}
+	var client FeeHistoryClient
+	if e.web3 != nil {
+		client = e.web3
+	}
+	return NewFeeOracle(tracker, client)
+}
+
+// timestampResolver prefers searching headers over RPC, which works on any chain
+// without spending explorer API quota, and falls back to Etherscan
+func (e *EtherealFacade) timestampResolver() TimestampResolver {
+	if e.web3 != nil {
+		return NewRPCBlockResolver(e.web3, e.cache)
+	}
+	if e.etherscan != nil {
+		return e.etherscan
+	}
+	return nil
+}
+
+func (e *EtherealFacade) abiProvider() EtherscanClient {
+	if e.providers != nil {
+		return e.providers
+	}
+	// Avoid wrapping a nil *Etherscan in a non-nil interface
+	if e.etherscan != nil {
+		return e.etherscan
+	}
+	return nil
+}
+
 func (e *EtherealFacade) contracts() *Contracts {
-	return NewContracts(e.etherscan, e.web3, e.cache)
+	var client ChainClient
+	if e.web3 != nil {
+		client = e.web3
+	}
+	contracts := NewContracts(e.abiProvider(), client, e.cache)
+	if resolver := e.timestampResolver(); resolver != nil {
+		contracts.SetTimestampResolver(resolver)
+	}
+	if e.accounts != nil {
+		contracts.SetAccounts(e.accounts)
+	}
+	return contracts
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 )
@@ -43,23 +44,27 @@ type EtherscanConfig struct {
 	ChainID    int                             `json:"chain_id"`
 	Timeout    int                             `json:"timeout"`
 	Networks   map[string]EtherscanNetworkConfig `json:"networks"`
+	// URL overrides the per-chain API endpoint, e.g. for a self-hosted explorer
+	URL        string                          `json:"url"`
 }
 
 // Etherscan provides access to Etherscan API functionality
 type Etherscan struct {
-	config   EtherscanConfig
-	cache    *Cache
-	chainID  int
-	client   *http.Client
+	config       EtherscanConfig
+	cache        *Cache
+	chainID      int
+	client       *http.Client
+	pollInterval time.Duration
 }
 
 // NewEtherscan creates a new Etherscan instance
 func NewEtherscan(config EtherscanConfig, cache *Cache) *Etherscan {
 	return &Etherscan{
-		config:  config,
-		cache:   cache,
-		chainID: config.ChainID,
-		client:  &http.Client{Timeout: time.Duration(config.Timeout) * time.Second},
+		config:       config,
+		cache:        cache,
+		chainID:      config.ChainID,
+		client:       &http.Client{Timeout: time.Duration(config.Timeout) * time.Second},
+		pollInterval: defaultPollInterval,
 	}
 }
 
@@ -139,6 +144,44 @@ func (e *Etherscan) GetABI(address string) (string, error) {
 	return abi, nil
 }
 
+// GetContractABI gets the ABI for a given address, satisfying EtherscanClient
+func (e *Etherscan) GetContractABI(address string) (string, error) {
+	return e.GetABI(address)
+}
+
+// GetContractSource gets the verified source code for a given address
+func (e *Etherscan) GetContractSource(address string) (string, error) {
+	cacheKey := fmt.Sprintf("etherscan:source:%s", address)
+	if cached, err := e.cache.Get(cacheKey); err == nil {
+		return cached.(string), nil
+	}
+
+	params := map[string]string{
+		"module":  "contract",
+		"action":  "getsourcecode",
+		"address": address,
+	}
+
+	result, err := e.fetch(params)
+	if err != nil {
+		return "", err
+	}
+
+	var entries []struct {
+		SourceCode string `json:"SourceCode"`
+	}
+	if err := decodeResult(result, &entries); err != nil {
+		return "", fmt.Errorf("failed to parse source code: %w", err)
+	}
+	if len(entries) == 0 || entries[0].SourceCode == "" {
+		return "", &EtherscanError{Message: "contract source code not verified"}
+	}
+
+	source := entries[0].SourceCode
+	e.cache.Set(cacheKey, source)
+	return source, nil
+}
+
 type etherscanResponse struct {
 	Status  string      `json:"status"`
 	Message string      `json:"message"`
@@ -146,36 +189,99 @@ type etherscanResponse struct {
 }
 
 func (e *Etherscan) fetch(params map[string]string) (interface{}, error) {
-	endpoint := endpoints[e.chainID]
-	if endpoint == "" {
-		return nil, fmt.Errorf("unsupported chain ID: %d", e.chainID)
+	result, err := e.get(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Status != "1" {
+		return nil, &EtherscanError{Message: result.Message}
+	}
+
+	return result.Result, nil
+}
+
+// get performs a GET request and returns the raw response envelope, leaving
+// the interpretation of non-"1" statuses to the caller
+func (e *Etherscan) get(params map[string]string) (*etherscanResponse, error) {
+	endpoint, err := e.endpoint()
+	if err != nil {
+		return nil, err
 	}
 
-	url := fmt.Sprintf("%s/api?", endpoint)
+	query := fmt.Sprintf("%s/api?", endpoint)
 	for k, v := range params {
-		url += fmt.Sprintf("%s=%s&", k, v)
+		query += fmt.Sprintf("%s=%s&", k, v)
 	}
-	
-	network := getNetwork(e.chainID)
-	apiKey := e.config.Networks[network].Key
-	url += fmt.Sprintf("apiKey=%s", apiKey)
+	query += fmt.Sprintf("apiKey=%s", e.apiKey())
+
+	resp, err := e.client.Get(query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decodeEtherscanResponse(resp)
+}
 
-	resp, err := e.client.Get(url)
+// post submits params as a form body, for actions whose payload is too large
+// for a query string
+func (e *Etherscan) post(params map[string]string) (*etherscanResponse, error) {
+	endpoint, err := e.endpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+	form.Set("apiKey", e.apiKey())
+
+	resp, err := e.client.PostForm(fmt.Sprintf("%s/api", endpoint), form)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	return decodeEtherscanResponse(resp)
+}
+
+func (e *Etherscan) endpoint() (string, error) {
+	if e.config.URL != "" {
+		return e.config.URL, nil
+	}
+	endpoint := endpoints[e.chainID]
+	if endpoint == "" {
+		return "", fmt.Errorf("unsupported chain ID: %d", e.chainID)
+	}
+	return endpoint, nil
+}
+
+func (e *Etherscan) apiKey() string {
+	network := getNetwork(e.chainID)
+	return e.config.Networks[network].Key
+}
+
+func decodeEtherscanResponse(resp *http.Response) (*etherscanResponse, error) {
+	if resp.StatusCode != http.StatusOK {
+		return nil, &EtherscanError{Message: resp.Status}
+	}
+
 	var result etherscanResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, err
 	}
+	return &result, nil
+}
 
-	if result.Status != "1" {
-		return nil, &EtherscanError{Message: result.Message}
+// decodeResult converts a generically decoded Etherscan result into out
+func decodeResult(result interface{}, out interface{}) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
 	}
-
-	return result.Result, nil
+	return json.Unmarshal(raw, out)
 }
 
 func getNetwork(chainID int) string {
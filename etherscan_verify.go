@@ -0,0 +1,196 @@
+package ethereal
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultPollInterval = 5 * time.Second
+
+// Verification states reported for a submission GUID
+const (
+	VerificationPending  = "pending"
+	VerificationVerified = "verified"
+	VerificationFailed   = "failed"
+)
+
+// ErrVerificationTimeout is returned when a submission is still pending once the timeout elapses
+var ErrVerificationTimeout = errors.New("verification timed out")
+
+// VerificationRequest describes a source code verification submission
+type VerificationRequest struct {
+	Address              string
+	StandardJSONInput    string // solc Standard JSON input
+	ContractName         string // fully qualified, e.g. "contracts/Token.sol:Token"
+	CompilerVersion      string // e.g. "v0.8.24+commit.e11b9ed9"
+	ConstructorArguments string // ABI-encoded constructor arguments as hex
+	LicenseType          int
+}
+
+// VerificationStatus represents the state of a verification submission
+type VerificationStatus struct {
+	GUID    string
+	State   string
+	Message string
+}
+
+// VerifySourceCode submits Standard JSON input for verification and returns the submission GUID
+func (e *Etherscan) VerifySourceCode(req VerificationRequest) (string, error) {
+	if req.Address == "" {
+		return "", errors.New("address cannot be empty")
+	}
+	if req.StandardJSONInput == "" {
+		return "", errors.New("standard JSON input cannot be empty")
+	}
+	if req.ContractName == "" {
+		return "", errors.New("contract name cannot be empty")
+	}
+	if req.CompilerVersion == "" {
+		return "", errors.New("compiler version cannot be empty")
+	}
+
+	params := map[string]string{
+		"module":          "contract",
+		"action":          "verifysourcecode",
+		"contractaddress": req.Address,
+		"sourceCode":      req.StandardJSONInput,
+		"codeformat":      "solidity-standard-json-input",
+		"contractname":    req.ContractName,
+		"compilerversion": req.CompilerVersion,
+		// The misspelling is part of the Etherscan API
+		"constructorArguements": strings.TrimPrefix(req.ConstructorArguments, "0x"),
+	}
+	if req.LicenseType > 0 {
+		params["licenseType"] = strconv.Itoa(req.LicenseType)
+	}
+
+	return e.submitVerification(params)
+}
+
+// CheckVerifyStatus returns the current state of a source code verification
+func (e *Etherscan) CheckVerifyStatus(guid string) (*VerificationStatus, error) {
+	return e.checkVerification("checkverifystatus", guid)
+}
+
+// WaitForVerification polls a source code verification until it completes or the timeout elapses
+func (e *Etherscan) WaitForVerification(guid string, timeout time.Duration) (*VerificationStatus, error) {
+	return e.waitForVerification("checkverifystatus", guid, timeout)
+}
+
+// Verify submits a verification and waits for its outcome
+func (e *Etherscan) Verify(req VerificationRequest, timeout time.Duration) (*VerificationStatus, error) {
+	guid, err := e.VerifySourceCode(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit verification: %w", err)
+	}
+	return e.WaitForVerification(guid, timeout)
+}
+
+// VerifyProxyContract asks the explorer to link a proxy to its implementation.
+// expectedImplementation is optional; when set the explorer rejects any other implementation.
+func (e *Etherscan) VerifyProxyContract(address string, expectedImplementation string) (string, error) {
+	if address == "" {
+		return "", errors.New("address cannot be empty")
+	}
+
+	params := map[string]string{
+		"module":  "contract",
+		"action":  "verifyproxycontract",
+		"address": address,
+	}
+	if expectedImplementation != "" {
+		params["expectedimplementation"] = expectedImplementation
+	}
+
+	return e.submitVerification(params)
+}
+
+// CheckProxyVerification returns the current state of a proxy verification
+func (e *Etherscan) CheckProxyVerification(guid string) (*VerificationStatus, error) {
+	return e.checkVerification("checkproxyverification", guid)
+}
+
+// WaitForProxyVerification polls a proxy verification until it completes or the timeout elapses
+func (e *Etherscan) WaitForProxyVerification(guid string, timeout time.Duration) (*VerificationStatus, error) {
+	return e.waitForVerification("checkproxyverification", guid, timeout)
+}
+
+func (e *Etherscan) submitVerification(params map[string]string) (string, error) {
+	result, err := e.post(params)
+	if err != nil {
+		return "", err
+	}
+
+	// On failure the reason is in the result while the message is just "NOTOK"
+	message, _ := result.Result.(string)
+	if result.Status != "1" {
+		return "", &EtherscanError{Message: message}
+	}
+	if message == "" {
+		return "", errors.New("etherscan did not return a verification GUID")
+	}
+
+	return message, nil
+}
+
+func (e *Etherscan) checkVerification(action string, guid string) (*VerificationStatus, error) {
+	if guid == "" {
+		return nil, errors.New("guid cannot be empty")
+	}
+
+	params := map[string]string{
+		"module": "contract",
+		"action": action,
+		"guid":   guid,
+	}
+
+	result, err := e.get(params)
+	if err != nil {
+		return nil, err
+	}
+
+	message, _ := result.Result.(string)
+	status := &VerificationStatus{GUID: guid, Message: message}
+
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "pending") || strings.Contains(lower, "in progress"):
+		status.State = VerificationPending
+	case result.Status == "1" || strings.HasPrefix(lower, "already verified"):
+		status.State = VerificationVerified
+	default:
+		status.State = VerificationFailed
+	}
+
+	return status, nil
+}
+
+func (e *Etherscan) waitForVerification(action string, guid string, timeout time.Duration) (*VerificationStatus, error) {
+	deadline := time.Now().Add(timeout)
+	interval := e.pollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	for {
+		status, err := e.checkVerification(action, guid)
+		if err != nil {
+			return nil, err
+		}
+
+		switch status.State {
+		case VerificationVerified:
+			return status, nil
+		case VerificationFailed:
+			return status, &EtherscanError{Message: status.Message}
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return status, fmt.Errorf("%w: guid %s", ErrVerificationTimeout, guid)
+		}
+		time.Sleep(interval)
+	}
+}
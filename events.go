@@ -0,0 +1,189 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// EventRecord is a decoded event log
+type EventRecord struct {
+	Event       string
+	Signature   string
+	Address     string
+	BlockNumber uint64
+	BlockHash   string
+	Timestamp   time.Time
+	TxHash      string
+	TxIndex     uint
+	LogIndex    uint
+	// Removed is set when a log delivered by a subscription was dropped by a chain reorganization
+	Removed bool
+	// Args holds decoded arguments by name. Indexed strings, bytes, arrays and
+	// tuples are only available as the keccak256 hash stored in the topic.
+	Args map[string]interface{}
+}
+
+// eventQuery builds the eth_getLogs topics for an event; blockRange sets the range.
+// Filters on non-indexed arguments cannot be expressed as topics and are returned
+// to be applied after decoding.
+func eventQuery(address common.Address, event *abi.Event, filter EventFilter) (ethereum.FilterQuery, []argumentFilter, error) {
+	query := ethereum.FilterQuery{
+		Addresses: []common.Address{address},
+	}
+
+	// Anonymous events have no signature topic, so every log of the contract is a candidate
+	var topic0 []common.Hash
+	if !event.Anonymous {
+		topic0 = []common.Hash{event.ID}
+	}
+	query.Topics = [][]common.Hash{topic0}
+
+	if len(filter.Topics) > 3 {
+		return query, nil, errors.New("at most 3 topics can be filtered besides the event signature")
+	}
+	for _, topic := range filter.Topics {
+		if topic == "" {
+			query.Topics = append(query.Topics, nil)
+			continue
+		}
+		hash, err := parseTopic(topic)
+		if err != nil {
+			return query, nil, err
+		}
+		query.Topics = append(query.Topics, []common.Hash{hash})
+	}
+
+	topics, dataFilters, err := applyArgumentFilters(query.Topics, event, filter.ArgumentFilters)
+	if err != nil {
+		return query, nil, err
+	}
+	query.Topics = topics
+
+	return query, dataFilters, nil
+}
+
+func (c *Contracts) decodeEventLogs(event *abi.Event, logs []types.Log, filters []argumentFilter) ([]EventRecord, error) {
+	records := make([]EventRecord, 0, len(logs))
+	for _, log := range logs {
+		args, err := decodeEventLog(event, log)
+		if err != nil {
+			// Logs of other events can only match when the event is anonymous
+			if event.Anonymous {
+				continue
+			}
+			return nil, fmt.Errorf("failed to decode %s in tx %s: %w", event.Sig, log.TxHash.Hex(), err)
+		}
+		if !matchesArgumentFilters(args, filters) {
+			continue
+		}
+
+		timestamp, err := c.blockTimestamp(log.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, eventRecord(event, log, args, timestamp))
+	}
+	return records, nil
+}
+
+func eventRecord(event *abi.Event, log types.Log, args map[string]interface{}, timestamp time.Time) EventRecord {
+	return EventRecord{
+		Event:       event.RawName,
+		Signature:   event.Sig,
+		Address:     log.Address.Hex(),
+		BlockNumber: log.BlockNumber,
+		BlockHash:   log.BlockHash.Hex(),
+		Timestamp:   timestamp,
+		TxHash:      log.TxHash.Hex(),
+		TxIndex:     log.TxIndex,
+		LogIndex:    log.Index,
+		Removed:     log.Removed,
+		Args:        args,
+	}
+}
+
+// decodeEventLog decodes indexed arguments from topics and the rest from data
+func decodeEventLog(event *abi.Event, log types.Log) (map[string]interface{}, error) {
+	topics := log.Topics
+	if !event.Anonymous {
+		if len(topics) == 0 || topics[0] != event.ID {
+			return nil, errors.New("log signature does not match event")
+		}
+		topics = topics[1:]
+	}
+
+	inputs := namedArguments(event.Inputs)
+
+	var indexed abi.Arguments
+	for _, input := range inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if len(topics) != len(indexed) {
+		return nil, fmt.Errorf("expected %d indexed topics, got %d", len(indexed), len(topics))
+	}
+
+	args := make(map[string]interface{}, len(inputs))
+	for i, arg := range indexed {
+		// go-ethereum refuses to reconstruct tuples, but like other dynamic types only the hash is stored
+		if arg.Type.T == abi.TupleTy {
+			args[arg.Name] = topics[i]
+			continue
+		}
+		if err := abi.ParseTopicsIntoMap(args, abi.Arguments{arg}, topics[i:i+1]); err != nil {
+			return nil, fmt.Errorf("failed to decode indexed argument %s: %w", arg.Name, err)
+		}
+	}
+
+	if err := inputs.UnpackIntoMap(args, log.Data); err != nil {
+		return nil, fmt.Errorf("failed to decode data: %w", err)
+	}
+
+	return args, nil
+}
+
+// namedArguments names unnamed arguments after their position so none are lost in maps
+func namedArguments(args abi.Arguments) abi.Arguments {
+	named := make(abi.Arguments, len(args))
+	copy(named, args)
+	for i := range named {
+		if named[i].Name == "" {
+			named[i].Name = fmt.Sprintf("arg%d", i)
+		}
+	}
+	return named
+}
+
+func (c *Contracts) blockTimestamp(number uint64) (time.Time, error) {
+	cacheKey := fmt.Sprintf("block_timestamp_%d", number)
+	if cached, err := c.cache.Get(cacheKey); err == nil {
+		return cached.(time.Time), nil
+	}
+
+	header, err := c.client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(number))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get block %d: %w", number, err)
+	}
+
+	timestamp := time.Unix(int64(header.Time), 0).UTC()
+	c.cache.Set(cacheKey, timestamp)
+	return timestamp, nil
+}
+
+func parseTopic(topic string) (common.Hash, error) {
+	raw := common.FromHex(topic)
+	if len(raw) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("invalid topic %q: expected 32 bytes", topic)
+	}
+	return common.BytesToHash(raw), nil
+}
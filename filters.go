@@ -0,0 +1,173 @@
+package ethereal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// argumentFilter holds the accepted values of a non-indexed event argument,
+// which can only be checked once the log data is decoded
+type argumentFilter struct {
+	name   string
+	values []interface{}
+}
+
+// matchesArgumentFilters reports whether the decoded arguments satisfy every filter
+func matchesArgumentFilters(args map[string]interface{}, filters []argumentFilter) bool {
+	for _, filter := range filters {
+		matched := false
+		for _, value := range filter.values {
+			if valuesEqual(value, args[filter.name]) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// applyArgumentFilters encodes filters on indexed arguments into the query topics
+// and returns the filters on non-indexed arguments. A filter value may be a single
+// value or a slice of values, any of which matches.
+func applyArgumentFilters(topics [][]common.Hash, event *abi.Event, filters map[string]interface{}) ([][]common.Hash, []argumentFilter, error) {
+	// Anonymous events have no signature topic, so indexed arguments start at topic0
+	offset := 1
+	if event.Anonymous {
+		offset = 0
+	}
+
+	inputs := namedArguments(event.Inputs)
+	positions := make(map[string]int)
+	arguments := make(map[string]abi.Argument)
+	indexed := 0
+	for _, input := range inputs {
+		arguments[input.Name] = input
+		if input.Indexed {
+			positions[input.Name] = offset + indexed
+			indexed++
+		}
+	}
+
+	// Sorted so that errors are deterministic
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var dataFilters []argumentFilter
+	for _, name := range names {
+		input, ok := arguments[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("event %s has no argument %q", event.Sig, name)
+		}
+
+		values := filterValues(input.Type, filters[name])
+		if len(values) == 0 {
+			return nil, nil, fmt.Errorf("no values given for argument %s", name)
+		}
+
+		if !input.Indexed {
+			converted := make([]interface{}, len(values))
+			for i, value := range values {
+				v, err := convertValue(input.Type, value)
+				if err != nil {
+					return nil, nil, fmt.Errorf("invalid filter value for argument %s (%s): %w", name, input.Type.String(), err)
+				}
+				converted[i] = v
+			}
+			dataFilters = append(dataFilters, argumentFilter{name: name, values: converted})
+			continue
+		}
+
+		hashes := make([]common.Hash, len(values))
+		for i, value := range values {
+			hash, err := topicValue(input.Type, value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid filter value for argument %s (%s): %w", name, input.Type.String(), err)
+			}
+			hashes[i] = hash
+		}
+
+		position := positions[name]
+		for len(topics) <= position {
+			topics = append(topics, nil)
+		}
+		if topics[position] != nil {
+			return nil, nil, fmt.Errorf("argument %s conflicts with the filter on topic%d", name, position)
+		}
+		topics[position] = hashes
+	}
+
+	return topics, dataFilters, nil
+}
+
+// filterValues expands a list of alternatives. Byte slices and values of array
+// arguments are a single value, so alternatives for those must be []interface{}.
+func filterValues(t abi.Type, value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		if t.T != abi.SliceTy && t.T != abi.ArrayTy {
+			return list
+		}
+		if len(list) > 0 && reflect.ValueOf(list[0]).Kind() == reflect.Slice {
+			return list
+		}
+		return []interface{}{value}
+	}
+
+	rv := reflect.ValueOf(value)
+	if t.T == abi.SliceTy || t.T == abi.ArrayTy || rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return []interface{}{value}
+	}
+
+	values := make([]interface{}, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values
+}
+
+// topicValue encodes a value the way it is stored in the topic of an indexed argument
+func topicValue(t abi.Type, value interface{}) (common.Hash, error) {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		// Only the keccak256 hash of dynamic and composite values is stored, which may be given directly
+		if hash, ok := value.(common.Hash); ok {
+			return hash, nil
+		}
+		switch t.T {
+		case abi.StringTy:
+			s, ok := value.(string)
+			if !ok {
+				return common.Hash{}, fmt.Errorf("cannot convert %T to string", value)
+			}
+			return crypto.Keccak256Hash([]byte(s)), nil
+		case abi.BytesTy:
+			b, err := convertBytes(value)
+			if err != nil {
+				return common.Hash{}, err
+			}
+			return crypto.Keccak256Hash(b), nil
+		default:
+			return common.Hash{}, fmt.Errorf("indexed %s arguments can only be filtered by their topic hash", t.String())
+		}
+	}
+
+	converted, err := convertValue(t, value)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	packed, err := abi.Arguments{{Type: t}}.Pack(converted)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(packed), nil
+}
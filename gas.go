@@ -0,0 +1,284 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+const feeHistoryBlocks = 20
+
+// Reward percentiles requested from eth_feeHistory for the slow, standard and fast tiers
+var feeHistoryPercentiles = []float64{10, 50, 90}
+
+// FeeHistoryClient interface defines the RPC methods required by the fee oracle
+type FeeHistoryClient interface {
+	FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error)
+}
+
+// GasTrackerClient interface defines the explorer methods required by the fee oracle
+type GasTrackerClient interface {
+	GetGasOracle() (*GasOracle, error)
+	GetConfirmationTime(gasPrice *big.Int) (time.Duration, error)
+}
+
+// GasOracle represents Etherscan's gas oracle prices, in wei
+type GasOracle struct {
+	LastBlock       int64
+	SafeGasPrice    *big.Int
+	ProposeGasPrice *big.Int
+	FastGasPrice    *big.Int
+	SuggestBaseFee  *big.Int
+}
+
+// FeeSuggestion is an EIP-1559 fee pair for one speed tier
+type FeeSuggestion struct {
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	// EstimatedWait is only set by SuggestFeesWithWaits, and zero when no
+	// confirmation time estimate is available
+	EstimatedWait time.Duration
+}
+
+// FeeSuggestions contains slow, standard and fast fee suggestions for a chain
+type FeeSuggestions struct {
+	BaseFee  *big.Int
+	Slow     FeeSuggestion
+	Standard FeeSuggestion
+	Fast     FeeSuggestion
+}
+
+// FeeOracle suggests transaction fees from eth_feeHistory and the explorer gas tracker.
+// Either source may be nil on chains where it is unavailable.
+type FeeOracle struct {
+	etherscan GasTrackerClient
+	client    FeeHistoryClient
+}
+
+// NewFeeOracle creates a new FeeOracle instance
+func NewFeeOracle(etherscan GasTrackerClient, client FeeHistoryClient) *FeeOracle {
+	return &FeeOracle{
+		etherscan: etherscan,
+		client:    client,
+	}
+}
+
+// SuggestFees returns fee suggestions for the next block. Base fee comes from the
+// RPC node when available; each tier's priority fee is the higher of the two sources.
+// Confirmation times are not estimated, see SuggestFeesWithWaits.
+func (o *FeeOracle) SuggestFees() (*FeeSuggestions, error) {
+	var baseFee *big.Int
+	priority := make([]*big.Int, len(feeHistoryPercentiles))
+
+	historyErr := errors.New("no RPC client configured")
+	if o.client != nil {
+		var history *ethereum.FeeHistory
+		history, historyErr = o.client.FeeHistory(context.Background(), feeHistoryBlocks, nil, feeHistoryPercentiles)
+		if historyErr == nil {
+			var tips []*big.Int
+			if baseFee, tips, historyErr = feesFromHistory(history); historyErr == nil {
+				copy(priority, tips)
+			}
+		}
+	}
+
+	oracleErr := errors.New("no gas tracker configured")
+	if o.etherscan != nil {
+		var oracle *GasOracle
+		oracle, oracleErr = o.etherscan.GetGasOracle()
+		if oracleErr == nil {
+			oracleBaseFee, tips := feesFromGasOracle(oracle)
+			if baseFee == nil {
+				baseFee = oracleBaseFee
+			}
+			for i := range priority {
+				priority[i] = maxBig(priority[i], tips[i])
+			}
+		}
+	}
+
+	if historyErr != nil && oracleErr != nil {
+		return nil, fmt.Errorf("failed to get fee data: fee history: %v; gas oracle: %v", historyErr, oracleErr)
+	}
+
+	// Higher tiers never pay less than lower ones
+	for i := 1; i < len(priority); i++ {
+		if priority[i].Cmp(priority[i-1]) < 0 {
+			priority[i] = new(big.Int).Set(priority[i-1])
+		}
+	}
+
+	suggestions := &FeeSuggestions{
+		BaseFee:  baseFee,
+		Slow:     suggestion(baseFee, priority[0]),
+		Standard: suggestion(baseFee, priority[1]),
+		Fast:     suggestion(baseFee, priority[2]),
+	}
+
+	return suggestions, nil
+}
+
+// SuggestFeesWithWaits returns fee suggestions with the explorer's estimated confirmation
+// time of each tier. The estimates take an explorer request per tier and are only
+// meaningful on Ethereum mainnet.
+func (o *FeeOracle) SuggestFeesWithWaits() (*FeeSuggestions, error) {
+	suggestions, err := o.SuggestFees()
+	if err != nil || o.etherscan == nil {
+		return suggestions, err
+	}
+	for _, tier := range []*FeeSuggestion{&suggestions.Slow, &suggestions.Standard, &suggestions.Fast} {
+		effective := new(big.Int).Add(suggestions.BaseFee, tier.MaxPriorityFeePerGas)
+		if wait, err := o.etherscan.GetConfirmationTime(effective); err == nil {
+			tier.EstimatedWait = wait
+		}
+	}
+	return suggestions, nil
+}
+
+// suggestion doubles the base fee so the fee cap survives several consecutive full blocks
+func suggestion(baseFee *big.Int, priority *big.Int) FeeSuggestion {
+	maxFee := new(big.Int).Mul(baseFee, big.NewInt(2))
+	maxFee.Add(maxFee, priority)
+
+	return FeeSuggestion{
+		MaxFeePerGas:         maxFee,
+		MaxPriorityFeePerGas: priority,
+	}
+}
+
+// feesFromHistory returns the next block's base fee and the median reward for each percentile
+func feesFromHistory(history *ethereum.FeeHistory) (*big.Int, []*big.Int, error) {
+	if len(history.BaseFee) == 0 {
+		return nil, nil, errors.New("fee history returned no base fees")
+	}
+	// The last entry is the base fee of the next, not yet mined, block
+	baseFee := history.BaseFee[len(history.BaseFee)-1]
+
+	tips := make([]*big.Int, len(feeHistoryPercentiles))
+	for i := range tips {
+		var rewards []*big.Int
+		for block, blockRewards := range history.Reward {
+			// Empty blocks report zero rewards that say nothing about demand
+			if block < len(history.GasUsedRatio) && history.GasUsedRatio[block] == 0 {
+				continue
+			}
+			if i < len(blockRewards) {
+				rewards = append(rewards, blockRewards[i])
+			}
+		}
+		tips[i] = medianBig(rewards)
+	}
+
+	return baseFee, tips, nil
+}
+
+// feesFromGasOracle splits the explorer's safe, propose and fast prices into base fee and tips
+func feesFromGasOracle(oracle *GasOracle) (*big.Int, []*big.Int) {
+	prices := []*big.Int{oracle.SafeGasPrice, oracle.ProposeGasPrice, oracle.FastGasPrice}
+	tips := make([]*big.Int, len(prices))
+	for i, price := range prices {
+		tips[i] = new(big.Int).Sub(price, oracle.SuggestBaseFee)
+		if tips[i].Sign() < 0 {
+			tips[i].SetInt64(0)
+		}
+	}
+	return oracle.SuggestBaseFee, tips
+}
+
+// GetGasOracle returns the explorer's current gas price recommendations
+func (e *Etherscan) GetGasOracle() (*GasOracle, error) {
+	params := map[string]string{
+		"module": "gastracker",
+		"action": "gasoracle",
+	}
+
+	result, err := e.fetch(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		LastBlock       string `json:"LastBlock"`
+		SafeGasPrice    string `json:"SafeGasPrice"`
+		ProposeGasPrice string `json:"ProposeGasPrice"`
+		FastGasPrice    string `json:"FastGasPrice"`
+		SuggestBaseFee  string `json:"suggestBaseFee"`
+	}
+	if err := decodeResult(result, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse gas oracle: %w", err)
+	}
+
+	oracle := &GasOracle{}
+	if oracle.LastBlock, err = strconv.ParseInt(raw.LastBlock, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid last block %q: %w", raw.LastBlock, err)
+	}
+	for _, field := range []struct {
+		value string
+		out   **big.Int
+	}{
+		{raw.SafeGasPrice, &oracle.SafeGasPrice},
+		{raw.ProposeGasPrice, &oracle.ProposeGasPrice},
+		{raw.FastGasPrice, &oracle.FastGasPrice},
+		{raw.SuggestBaseFee, &oracle.SuggestBaseFee},
+	} {
+		if *field.out, err = gweiToWei(field.value); err != nil {
+			return nil, err
+		}
+	}
+
+	return oracle, nil
+}
+
+// GetConfirmationTime returns the explorer's estimated confirmation time for a gas price in wei
+func (e *Etherscan) GetConfirmationTime(gasPrice *big.Int) (time.Duration, error) {
+	params := map[string]string{
+		"module":   "gastracker",
+		"action":   "gasestimate",
+		"gasprice": gasPrice.String(),
+	}
+
+	result, err := e.fetch(params)
+	if err != nil {
+		return 0, err
+	}
+
+	seconds, err := strconv.ParseInt(fmt.Sprint(result), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid confirmation time %v: %w", result, err)
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// gweiToWei converts a decimal gwei amount such as "12.5" to wei
+func gweiToWei(gwei string) (*big.Int, error) {
+	amount, ok := new(big.Rat).SetString(gwei)
+	if !ok {
+		return nil, fmt.Errorf("invalid gwei amount %q", gwei)
+	}
+	amount.Mul(amount, new(big.Rat).SetInt64(1e9))
+	return new(big.Int).Quo(amount.Num(), amount.Denom()), nil
+}
+
+func medianBig(values []*big.Int) *big.Int {
+	if len(values) == 0 {
+		return big.NewInt(0)
+	}
+	sorted := make([]*big.Int, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+	return sorted[len(sorted)/2]
+}
+
+func maxBig(a *big.Int, b *big.Int) *big.Int {
+	if a == nil || (b != nil && b.Cmp(a) > 0) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,485 @@
+package ethereal
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// upgradedTopic is the topic of Upgraded(address), emitted by EIP-1967 proxies and beacons
+	upgradedTopic = common.HexToHash("0xbc7cd75a20ee27fd9adebab32041f755214dbc6bffa90cc0225b39da2e5c2d3b")
+	// beaconUpgradedTopic is the topic of BeaconUpgraded(address)
+	beaconUpgradedTopic = common.HexToHash("0x1cf3b03a6cf19fa2baba4df148e9dcabedea7f8a5c07840e207e5c089be95d3e")
+)
+
+// ImplementationPeriod is an implementation of a proxy and the blocks it was active in
+type ImplementationPeriod struct {
+	Implementation string // empty while the proxy had no implementation
+	Beacon         string // the beacon the implementation came from, for beacon proxies
+	FromBlock      uint64
+	ToBlock        uint64
+}
+
+// proxyState is what a proxy delegates to at some block
+type proxyState struct {
+	implementation common.Address
+	beacon         common.Address
+}
+
+// hasHistory reports whether the implementation of a proxy type can change and be
+// reconstructed from storage and Upgraded events
+func hasHistory(proxyType string) bool {
+	switch proxyType {
+	case "EIP1967", "EIP1967Beacon", "EIP1822", "ZeppelinOS":
+		return true
+	}
+	return false
+}
+
+// GetProxyHistory reconstructs the implementations of an upgradeable proxy between two
+// blocks, which take the same values as EventFilter's FromBlock and ToBlock. Changes are
+// found through Upgraded and BeaconUpgraded events of the proxy and Upgraded events of
+// its beacons, and the implementation at fromBlock through the last of them before it.
+// Storage is only read at fromBlock when no such event precedes it; where that needs an
+// archive node the node does not provide, the current implementation is assumed.
+func (c *Contracts) GetProxyHistory(address string, fromBlock, toBlock interface{}) ([]ImplementationPeriod, error) {
+	state, ok := c.client.(StateClient)
+	if !ok {
+		return nil, errNoRPCClient
+	}
+
+	info, err := c.GetProxyInfo(address)
+	if err != nil {
+		return nil, err
+	}
+	if !hasHistory(info.ProxyType) {
+		return nil, fmt.Errorf("implementation history is not available for %s", address)
+	}
+
+	ctx := context.Background()
+	from, to, err := c.historyRange(ctx, EventFilter{FromBlock: fromBlock, ToBlock: toBlock})
+	if err != nil {
+		return nil, err
+	}
+	return c.proxyHistory(ctx, state, common.HexToAddress(address), currentProxyState(info), from, to)
+}
+
+// historyRange resolves the block range of a filter, ending at the latest block when open
+func (c *Contracts) historyRange(ctx context.Context, filter EventFilter) (uint64, uint64, error) {
+	from, to, err := c.blockRange(ctx, filter)
+	if err != nil {
+		return 0, 0, err
+	}
+	if to == nil {
+		header, err := c.client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get latest block: %w", err)
+		}
+		to = header.Number
+	}
+	if from.Cmp(to) > 0 {
+		return 0, 0, fmt.Errorf("fromBlock %s is after the latest block %s", from, to)
+	}
+	return from.Uint64(), to.Uint64(), nil
+}
+
+// currentProxyState is what a proxy delegates to at the latest block
+func currentProxyState(info *ProxyInfo) proxyState {
+	current := proxyState{implementation: common.HexToAddress(info.Implementation)}
+	if len(info.Chain) > 0 {
+		current.implementation = common.HexToAddress(info.Chain[0])
+	}
+	if info.Beacon != "" {
+		current.beacon = common.HexToAddress(info.Beacon)
+	}
+	return current
+}
+
+func (c *Contracts) proxyHistory(ctx context.Context, state StateClient, proxy common.Address, current proxyState, from, to uint64) ([]ImplementationPeriod, error) {
+	// Upgrades are read from the creation of the proxy, as the implementation at from is
+	// the target of the last one before it; eth_getLogs is served by any node, unlike
+	// storage at old blocks
+	logs, err := c.upgradeLogs(ctx, state, proxy, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upgrades of %s: %w", proxy.Hex(), err)
+	}
+
+	var start proxyState
+	known := false
+	for len(logs) > 0 && logs[0].BlockNumber < from {
+		if isUpgrade(logs[0]) {
+			start, known = upgradeState(logs[0]), true
+		}
+		logs = logs[1:]
+	}
+	if !known {
+		start, err = implementationAt(ctx, state, proxy, from)
+		if isMissingState(err) {
+			start = current
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	periods := splitPeriods(start, from, to, logs, upgradeState)
+
+	// Beacons are upgraded independently of the proxies that use them
+	var history []ImplementationPeriod
+	for _, period := range periods {
+		if period.Beacon == "" {
+			history = append(history, period)
+			continue
+		}
+		split, err := c.beaconHistory(ctx, state, period)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, split...)
+	}
+	return history, nil
+}
+
+// beaconHistory splits a period of a beacon proxy at the upgrades of its beacon. The
+// implementation at the start of the period is the target of the last upgrade before it,
+// or else the one the beacon returned then, or returns now without an archive node.
+func (c *Contracts) beaconHistory(ctx context.Context, state StateClient, period ImplementationPeriod) ([]ImplementationPeriod, error) {
+	beacon := common.HexToAddress(period.Beacon)
+	all, err := c.upgradeLogs(ctx, state, beacon, period.ToBlock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upgrades of beacon %s: %w", beacon.Hex(), err)
+	}
+	var logs []types.Log
+	for _, log := range all {
+		if len(log.Topics) > 0 && log.Topics[0] == upgradedTopic {
+			logs = append(logs, log)
+		}
+	}
+
+	start := proxyState{beacon: beacon}
+	known := false
+	for len(logs) > 0 && logs[0].BlockNumber < period.FromBlock {
+		if isUpgrade(logs[0]) {
+			start.implementation, known = upgradeState(logs[0]).implementation, true
+		}
+		logs = logs[1:]
+	}
+	if !known && period.Implementation != "" {
+		start.implementation, known = common.HexToAddress(period.Implementation), true
+	}
+	if !known {
+		start.implementation, err = callAddress(ctx, state, beacon, implementationSelector, new(big.Int).SetUint64(period.FromBlock))
+		if isMissingState(err) {
+			start.implementation, err = callAddress(ctx, state, beacon, implementationSelector, nil)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get implementation from beacon %s: %w", beacon.Hex(), err)
+		}
+	}
+
+	return splitPeriods(start, period.FromBlock, period.ToBlock, logs, func(log types.Log) proxyState {
+		return proxyState{implementation: upgradeState(log).implementation, beacon: beacon}
+	}), nil
+}
+
+// upgradeLogs gets the Upgraded and BeaconUpgraded logs of a proxy or beacon from its
+// creation up to a block. They are cached per address, so that later calls only fetch
+// the blocks after the last one.
+func (c *Contracts) upgradeLogs(ctx context.Context, state StateClient, address common.Address, to uint64) ([]types.Log, error) {
+	cacheKey := fmt.Sprintf("upgrade_logs_%s", address.Hex())
+	var cached *upgradeLogRange
+	if value, err := c.cache.Get(cacheKey); err == nil {
+		cached = value.(*upgradeLogRange)
+	}
+
+	var from uint64
+	var logs []types.Log
+	if cached != nil {
+		if to <= cached.to {
+			return cached.until(to), nil
+		}
+		from, logs = cached.to+1, cached.logs
+	} else {
+		from = c.creationBlock(ctx, state, address)
+	}
+
+	fetched, err := c.fetchLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{address},
+		Topics:    [][]common.Hash{{upgradedTopic, beaconUpgradedTopic}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The cached slice is shared with earlier callers
+	logs = append(logs[:len(logs):len(logs)], fetched...)
+
+	if err := c.cache.Set(cacheKey, &upgradeLogRange{logs: logs, to: to}); err != nil {
+		return nil, fmt.Errorf("failed to cache upgrades: %w", err)
+	}
+	return logs, nil
+}
+
+// upgradeLogRange is the upgrade logs of an address up to and including block to
+type upgradeLogRange struct {
+	logs []types.Log
+	to   uint64
+}
+
+func (r *upgradeLogRange) until(block uint64) []types.Log {
+	n := sort.Search(len(r.logs), func(i int) bool { return r.logs[i].BlockNumber > block })
+	return r.logs[:n:n]
+}
+
+// creationBlock finds the block a contract was deployed in, through the explorer's
+// creation record or else by searching for the first block with its code. Without
+// either, e.g. on a node without archive state, it returns the first block.
+func (c *Contracts) creationBlock(ctx context.Context, state StateClient, address common.Address) uint64 {
+	cacheKey := fmt.Sprintf("creation_block_%s", address.Hex())
+	if cached, err := c.cache.Get(cacheKey); err == nil {
+		return cached.(uint64)
+	}
+
+	block, ok := c.explorerCreationBlock(ctx, address)
+	if !ok {
+		block, ok = c.searchCreationBlock(ctx, state, address)
+	}
+	if !ok {
+		return 0
+	}
+	// Failing to cache only costs another lookup
+	_ = c.cache.Set(cacheKey, block)
+	return block
+}
+
+func (c *Contracts) explorerCreationBlock(ctx context.Context, address common.Address) (uint64, bool) {
+	explorer, ok := c.etherscan.(CreationClient)
+	if !ok {
+		return 0, false
+	}
+	receipts, ok := c.client.(ReceiptClient)
+	if !ok {
+		return 0, false
+	}
+	creations, err := explorer.GetContractCreation([]string{address.Hex()})
+	if err != nil || len(creations) == 0 {
+		return 0, false
+	}
+	receipt, err := receipts.TransactionReceipt(ctx, common.HexToHash(creations[0].TxHash))
+	if err != nil || receipt.BlockNumber == nil {
+		return 0, false
+	}
+	return receipt.BlockNumber.Uint64(), true
+}
+
+// searchCreationBlock bisects the chain for the first block with code at an address
+func (c *Contracts) searchCreationBlock(ctx context.Context, state StateClient, address common.Address) (uint64, bool) {
+	header, err := c.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, false
+	}
+	low, high := uint64(0), header.Number.Uint64()
+	if code, err := state.CodeAt(ctx, address, header.Number); err != nil || len(code) == 0 {
+		return 0, false
+	}
+	for low < high {
+		mid := low + (high-low)/2
+		code, err := state.CodeAt(ctx, address, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return 0, false
+		}
+		if len(code) > 0 {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	return low, true
+}
+
+// Error fragments nodes return for state they pruned, which only archive nodes keep
+var missingStateErrors = []string{
+	"missing trie node",
+	"historical state",
+	"state unavailable",
+	"state is not available",
+	"is pruned",
+	"archive state",
+}
+
+func isMissingState(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, fragment := range missingStateErrors {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUpgrade(log types.Log) bool {
+	return !log.Removed && len(log.Topics) >= 2
+}
+
+// upgradeState is what a proxy delegates to after an Upgraded or BeaconUpgraded log.
+// The implementation behind a new beacon is found by beaconHistory.
+func upgradeState(log types.Log) proxyState {
+	target := common.BytesToAddress(log.Topics[1].Bytes())
+	if log.Topics[0] == beaconUpgradedTopic {
+		return proxyState{beacon: target}
+	}
+	return proxyState{implementation: target}
+}
+
+// splitPeriods splits a block range at the logs that change what a proxy delegates to.
+// A change applies from the block of its log onwards.
+func splitPeriods(start proxyState, from, to uint64, logs []types.Log, apply func(types.Log) proxyState) []ImplementationPeriod {
+	var periods []ImplementationPeriod
+	current, currentFrom := start, from
+	for _, log := range logs {
+		if !isUpgrade(log) {
+			continue
+		}
+		next := apply(log)
+		if next == current {
+			continue
+		}
+		if log.BlockNumber > currentFrom {
+			periods = append(periods, implementationPeriod(current, currentFrom, log.BlockNumber-1))
+		}
+		current, currentFrom = next, log.BlockNumber
+	}
+	return append(periods, implementationPeriod(current, currentFrom, to))
+}
+
+func implementationPeriod(state proxyState, from, to uint64) ImplementationPeriod {
+	period := ImplementationPeriod{FromBlock: from, ToBlock: to}
+	if state.implementation != (common.Address{}) {
+		period.Implementation = state.implementation.Hex()
+	}
+	if state.beacon != (common.Address{}) {
+		period.Beacon = state.beacon.Hex()
+	}
+	return period
+}
+
+// implementationAt reads the implementation slots of a proxy at a block
+func implementationAt(ctx context.Context, state StateClient, proxy common.Address, block uint64) (proxyState, error) {
+	blockNumber := new(big.Int).SetUint64(block)
+
+	implementation, err := readAddressSlot(ctx, state, proxy, eip1967ImplementationSlot, blockNumber)
+	if err != nil || implementation != (common.Address{}) {
+		return proxyState{implementation: implementation}, err
+	}
+
+	beacon, err := readAddressSlot(ctx, state, proxy, eip1967BeaconSlot, blockNumber)
+	if err != nil {
+		return proxyState{}, err
+	}
+	if beacon != (common.Address{}) {
+		// The implementation behind the beacon is found by beaconHistory
+		return proxyState{beacon: beacon}, nil
+	}
+
+	for _, legacy := range legacyImplementationSlots {
+		implementation, err := readAddressSlot(ctx, state, proxy, legacy.slot, blockNumber)
+		if err != nil || implementation != (common.Address{}) {
+			return proxyState{implementation: implementation}, err
+		}
+	}
+	return proxyState{}, nil
+}
+
+// historicalEvents gets the events of an upgradeable proxy, decoding each block range
+// with the ABI of the implementation active in it, so that events of earlier versions
+// keep their original signatures. It reports false for contracts without a history.
+func (c *Contracts) historicalEvents(address string, event string, filter EventFilter) ([]EventRecord, bool, error) {
+	state, ok := c.client.(StateClient)
+	if !ok || !common.IsHexAddress(address) {
+		return nil, false, nil
+	}
+	info, err := c.GetProxyInfo(address)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to detect proxy: %w", err)
+	}
+	if !hasHistory(info.ProxyType) {
+		return nil, false, nil
+	}
+
+	ctx := context.Background()
+	from, to, err := c.historyRange(ctx, filter)
+	if err != nil {
+		return nil, true, err
+	}
+	proxy := common.HexToAddress(address)
+	periods, err := c.proxyHistory(ctx, state, proxy, currentProxyState(info), from, to)
+	if err != nil {
+		return nil, true, err
+	}
+
+	proxyABI, proxyErr := c.GetABI(address, false)
+	var (
+		records []EventRecord
+		found   bool
+		lastErr error = fmt.Errorf("event %s does not exist in contract ABI", event)
+	)
+	for _, period := range periods {
+		periodABI, err := c.periodABI(period, proxyABI, proxyErr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		abiEvent, err := periodABI.Event(event)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		found = true
+
+		query, dataFilters, err := eventQuery(proxy, abiEvent, filter)
+		if err != nil {
+			return nil, true, err
+		}
+		query.FromBlock = new(big.Int).SetUint64(period.FromBlock)
+		query.ToBlock = new(big.Int).SetUint64(period.ToBlock)
+		logs, err := c.fetchLogs(ctx, query)
+		if err != nil {
+			return nil, true, err
+		}
+		decoded, err := c.decodeEventLogs(abiEvent, logs, dataFilters)
+		if err != nil {
+			return nil, true, err
+		}
+		records = append(records, decoded...)
+	}
+
+	if !found {
+		return nil, true, lastErr
+	}
+	return records, true, nil
+}
+
+// periodABI is the ABI of a proxy while an implementation was active
+func (c *Contracts) periodABI(period ImplementationPeriod, proxyABI *ContractABI, proxyErr error) (*ContractABI, error) {
+	if period.Implementation == "" {
+		return proxyABI, proxyErr
+	}
+	implementationABI, err := c.GetABI(period.Implementation, false)
+	if err != nil {
+		return proxyABI, proxyErr
+	}
+	if proxyErr != nil {
+		return implementationABI, nil
+	}
+	return MergeABIs(implementationABI, proxyABI)
+}
@@ -0,0 +1,196 @@
+package ethereal
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	defaultLogChunkSize = 5000
+	defaultLogWorkers   = 4
+)
+
+// Error fragments providers use when an eth_getLogs range or result set is too large.
+// They must not match rate limiting errors such as "rate limit exceeded", which
+// splitting would only make worse.
+var logLimitErrors = []string{
+	"query returned more than",
+	"block range too large",
+	"block range is too large",
+	"range too large",
+	"exceed maximum block range",
+	"exceeds maximum block range",
+	"response size exceeded",
+	"response size should not",
+	"eth_getlogs is limited to",
+	"too many results",
+	"query timeout exceeded",
+	"logs over more than",
+}
+
+// LogFetchConfig controls how eth_getLogs queries are split
+type LogFetchConfig struct {
+	// ChunkSize is the number of blocks requested per query
+	ChunkSize uint64
+	// Workers is the number of chunks fetched concurrently
+	Workers int
+}
+
+// SetLogFetchConfig configures how GetEvents splits log queries; zero values keep the defaults
+func (c *Contracts) SetLogFetchConfig(config LogFetchConfig) {
+	c.logConfig = config
+}
+
+// fetchLogs runs query over its block range in chunks, bisecting any chunk the
+// provider rejects as too large, and returns logs in block and log index order
+func (c *Contracts) fetchLogs(parent context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	config := c.logConfig
+	if config.ChunkSize == 0 {
+		config.ChunkSize = defaultLogChunkSize
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultLogWorkers
+	}
+
+	from := uint64(0)
+	if query.FromBlock != nil {
+		from = query.FromBlock.Uint64()
+	}
+
+	var to uint64
+	if query.ToBlock != nil {
+		to = query.ToBlock.Uint64()
+	} else {
+		header, err := c.client.HeaderByNumber(parent, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest block: %w", err)
+		}
+		to = header.Number.Uint64()
+	}
+	if from > to {
+		return nil, nil
+	}
+
+	type chunk struct{ from, to uint64 }
+	var chunks []chunk
+	for start := from; start <= to; start += config.ChunkSize {
+		end := start + config.ChunkSize - 1
+		if end > to || end < start {
+			end = to
+		}
+		chunks = append(chunks, chunk{start, end})
+		if end == to {
+			break
+		}
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	results := make([][]types.Log, len(chunks))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	workers := config.Workers
+	if workers > len(chunks) {
+		workers = len(chunks)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				logs, err := c.fetchLogRange(ctx, query, chunks[i].from, chunks[i].to)
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				results[i] = logs
+			}
+		}()
+	}
+
+dispatch:
+	for i := range chunks {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+
+	var logs []types.Log
+	for _, chunkLogs := range results {
+		logs = append(logs, chunkLogs...)
+	}
+	sortLogs(logs)
+	return logs, nil
+}
+
+// fetchLogRange queries a single range, halving it for as long as the provider rejects it
+func (c *Contracts) fetchLogRange(ctx context.Context, query ethereum.FilterQuery, from uint64, to uint64) ([]types.Log, error) {
+	query.FromBlock = new(big.Int).SetUint64(from)
+	query.ToBlock = new(big.Int).SetUint64(to)
+
+	logs, err := c.client.FilterLogs(ctx, query)
+	if err == nil {
+		return logs, nil
+	}
+	if !isLogLimitError(err) {
+		return nil, fmt.Errorf("failed to get logs for blocks %d-%d: %w", from, to, err)
+	}
+	if from == to {
+		return nil, fmt.Errorf("block %d alone exceeds the provider's log limit: %w", from, err)
+	}
+
+	mid := from + (to-from)/2
+	left, err := c.fetchLogRange(ctx, query, from, mid)
+	if err != nil {
+		return nil, err
+	}
+	right, err := c.fetchLogRange(ctx, query, mid+1, to)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+func isLogLimitError(err error) bool {
+	message := strings.ToLower(err.Error())
+	for _, fragment := range logLimitErrors {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortLogs(logs []types.Log) {
+	sort.SliceStable(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+}
@@ -0,0 +1,190 @@
+package ethereal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Blockscout instances exposing the Etherscan-compatible API
+var blockscoutEndpoints = map[int]string{
+	1:     "https://eth.blockscout.com",
+	10:    "https://optimism.blockscout.com",
+	100:   "https://gnosis.blockscout.com",
+	137:   "https://polygon.blockscout.com",
+	8453:  "https://base.blockscout.com",
+	42161: "https://arbitrum.blockscout.com",
+}
+
+// BlockscoutConfig represents the Blockscout configuration
+type BlockscoutConfig struct {
+	ChainID int    `json:"chain_id"`
+	Timeout int    `json:"timeout"`
+	Key     string `json:"key"`
+	// URL overrides the per-chain instance, e.g. for a self-hosted Blockscout
+	URL string `json:"url"`
+}
+
+// Blockscout provides ABIs and sources through Blockscout's Etherscan-compatible API
+type Blockscout struct {
+	api   *Etherscan
+	cache *Cache
+}
+
+// NewBlockscout creates a new Blockscout instance
+func NewBlockscout(config BlockscoutConfig, cache *Cache) *Blockscout {
+	url := config.URL
+	if url == "" {
+		url = blockscoutEndpoints[config.ChainID]
+	}
+
+	api := NewEtherscan(EtherscanConfig{
+		ChainID: config.ChainID,
+		Timeout: config.Timeout,
+		URL:     url,
+		Networks: map[string]EtherscanNetworkConfig{
+			getNetwork(config.ChainID): {Key: config.Key},
+		},
+	}, cache)
+
+	return &Blockscout{
+		api:   api,
+		cache: cache,
+	}
+}
+
+// GetContractABI gets the ABI for a given address
+func (b *Blockscout) GetContractABI(address string) (string, error) {
+	if b.api.config.URL == "" {
+		return "", fmt.Errorf("no Blockscout instance for chain ID %d", b.api.chainID)
+	}
+
+	// Keyed separately from Etherscan since both share the cache
+	cacheKey := fmt.Sprintf("blockscout:abi:%d:%s", b.api.chainID, strings.ToLower(address))
+	if cached, err := b.cache.Get(cacheKey); err == nil {
+		return cached.(string), nil
+	}
+
+	params := map[string]string{
+		"module":  "contract",
+		"action":  "getabi",
+		"address": address,
+	}
+
+	result, err := b.api.fetch(params)
+	if err != nil {
+		return "", err
+	}
+
+	abi, ok := result.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected Blockscout ABI result for %s", address)
+	}
+	b.cache.Set(cacheKey, abi)
+	return abi, nil
+}
+
+// GetContractSource gets the verified source code for a given address
+func (b *Blockscout) GetContractSource(address string) (string, error) {
+	if b.api.config.URL == "" {
+		return "", fmt.Errorf("no Blockscout instance for chain ID %d", b.api.chainID)
+	}
+
+	cacheKey := fmt.Sprintf("blockscout:source:%d:%s", b.api.chainID, strings.ToLower(address))
+	if cached, err := b.cache.Get(cacheKey); err == nil {
+		return cached.(string), nil
+	}
+
+	params := map[string]string{
+		"module":  "contract",
+		"action":  "getsourcecode",
+		"address": address,
+	}
+
+	result, err := b.api.fetch(params)
+	if err != nil {
+		return "", err
+	}
+
+	var entries []struct {
+		SourceCode string `json:"SourceCode"`
+	}
+	if err := decodeResult(result, &entries); err != nil {
+		return "", fmt.Errorf("failed to parse source code: %w", err)
+	}
+	if len(entries) == 0 || entries[0].SourceCode == "" {
+		return "", fmt.Errorf("contract %s is not verified on Blockscout", address)
+	}
+
+	source := entries[0].SourceCode
+	b.cache.Set(cacheKey, source)
+	return source, nil
+}
+
+// NamedProvider is an ABI provider registered in a ChainedProvider
+type NamedProvider struct {
+	Name     string
+	Provider EtherscanClient
+}
+
+// ChainedProvider tries each provider in order until one answers. It satisfies
+// EtherscanClient, so it can be handed to NewContracts in place of Etherscan.
+type ChainedProvider struct {
+	providers []NamedProvider
+	mu        sync.RWMutex
+	answered  map[string]string
+}
+
+// NewChainedProvider creates a new ChainedProvider querying providers in the given order
+func NewChainedProvider(providers ...NamedProvider) *ChainedProvider {
+	return &ChainedProvider{
+		providers: providers,
+		answered:  make(map[string]string),
+	}
+}
+
+// GetContractABI gets the ABI from the first provider that has it
+func (p *ChainedProvider) GetContractABI(address string) (string, error) {
+	return p.first(address, "ABI", func(provider EtherscanClient) (string, error) {
+		return provider.GetContractABI(address)
+	})
+}
+
+// GetContractSource gets the source code from the first provider that has it
+func (p *ChainedProvider) GetContractSource(address string) (string, error) {
+	return p.first(address, "source", func(provider EtherscanClient) (string, error) {
+		return provider.GetContractSource(address)
+	})
+}
+
+// AnsweredBy returns the name of the provider that last answered for an address
+func (p *ChainedProvider) AnsweredBy(address string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	name, ok := p.answered[strings.ToLower(address)]
+	return name, ok
+}
+
+func (p *ChainedProvider) first(address string, what string, get func(EtherscanClient) (string, error)) (string, error) {
+	if len(p.providers) == 0 {
+		return "", errors.New("no ABI providers configured")
+	}
+
+	var failures []string
+	for _, named := range p.providers {
+		result, err := get(named.Provider)
+		if err == nil && result != "" {
+			p.mu.Lock()
+			p.answered[strings.ToLower(address)] = named.Name
+			p.mu.Unlock()
+			return result, nil
+		}
+		if err == nil {
+			err = errors.New("empty result")
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", named.Name, err))
+	}
+
+	return "", fmt.Errorf("no provider returned %s for %s (%s)", what, address, strings.Join(failures, "; "))
+}
@@ -0,0 +1,281 @@
+package ethereal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// EIP-1967 storage slots, keccak256("eip1967.proxy.<name>") - 1
+var (
+	eip1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	eip1967AdminSlot          = common.HexToHash("0xb53127684a568b3173ae13b9f8a6016e243e63b6e8ee1178d6a717850b5d6103")
+	eip1967BeaconSlot         = common.HexToHash("0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50")
+)
+
+var (
+	// eip1822ProxiableSlot is keccak256("PROXIABLE"), where EIP-1822 UUPS proxies keep the implementation
+	eip1822ProxiableSlot = common.HexToHash("0xc5f16f0fcc639fa48a6947836d9850f504798523bf8c9a3a87d5876cf622bcf7")
+	// zeppelinOSImplementationSlot is keccak256("org.zeppelinos.proxy.implementation"), used
+	// by OpenZeppelin proxies that predate EIP-1967
+	zeppelinOSImplementationSlot = common.HexToHash("0x7050c9e0f4ca769c69bd3a8ef740bc37934f8e2c036e5a723fd8ee048ed3f8c3")
+	// safeMasterCopySlot holds the singleton of Gnosis Safe proxies
+	safeMasterCopySlot = common.Hash{}
+)
+
+var (
+	// implementationSelector is the selector of implementation(), used by EIP-1967
+	// beacons and EIP-897 proxies
+	implementationSelector = []byte{0x5c, 0x60, 0xda, 0x1b}
+	// masterCopySelector is the selector of masterCopy(), answered by Gnosis Safe proxies
+	masterCopySelector = []byte{0xa6, 0x19, 0x48, 0x6e}
+)
+
+// EIP-1167 minimal proxy bytecode around the 20-byte implementation address
+var (
+	eip1167Prefix = common.FromHex("0x363d3d373d3d3d363d73")
+	eip1167Suffix = common.FromHex("0x5af43d82803e903d91602b57fd5bf3")
+)
+
+// legacyImplementationSlots are implementation slots of proxies that predate EIP-1967
+var legacyImplementationSlots = []struct {
+	slot      common.Hash
+	proxyType string
+}{
+	{eip1822ProxiableSlot, "EIP1822"},
+	{zeppelinOSImplementationSlot, "ZeppelinOS"},
+}
+
+// maxProxyChain bounds how many proxies are followed to reach an implementation
+const maxProxyChain = 8
+
+// StateClient interface defines the methods required to read contract state
+type StateClient interface {
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// detectProxy reads the proxy state of a contract at the latest block. When the
+// implementation is a proxy itself, the chain is followed to the final implementation.
+func (c *Contracts) detectProxy(ctx context.Context, state StateClient, address common.Address) (*ProxyInfo, error) {
+	info, err := c.detectProxyHop(ctx, state, address)
+	if err != nil || !info.IsProxy || info.ProxyType == "EIP2535" {
+		return info, err
+	}
+
+	visited := map[common.Address]bool{address: true}
+	info.Chain = []string{info.Implementation}
+	for {
+		next := common.HexToAddress(info.Implementation)
+		if visited[next] {
+			return nil, fmt.Errorf("proxy cycle detected: %s -> %s", address.Hex(), strings.Join(info.Chain, " -> "))
+		}
+		if len(info.Chain) > maxProxyChain {
+			return nil, fmt.Errorf("proxy chain of %s exceeds %d proxies", address.Hex(), maxProxyChain)
+		}
+		visited[next] = true
+
+		hop, err := c.detectProxyHop(ctx, state, next)
+		if err != nil {
+			return nil, err
+		}
+		if !hop.IsProxy {
+			return info, nil
+		}
+		if hop.ProxyType == "EIP2535" {
+			// A diamond behind a proxy ends the chain, its facets providing the functions
+			info.Facets = hop.Facets
+			return info, nil
+		}
+		info.Implementation = hop.Implementation
+		info.Chain = append(info.Chain, hop.Implementation)
+	}
+}
+
+// detectProxyHop detects whether a contract is a proxy, without following its implementation
+func (c *Contracts) detectProxyHop(ctx context.Context, state StateClient, address common.Address) (*ProxyInfo, error) {
+	info := &ProxyInfo{}
+	found := func(proxyType string, implementation common.Address) (*ProxyInfo, error) {
+		info.IsProxy = true
+		info.ProxyType = proxyType
+		info.Implementation = implementation.Hex()
+		return info, nil
+	}
+
+	code, err := state.CodeAt(ctx, address, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code of %s: %w", address.Hex(), err)
+	}
+	if implementation, ok := minimalProxyImplementation(code); ok {
+		return found("EIP1167", implementation)
+	}
+
+	admin, err := readAddressSlot(ctx, state, address, eip1967AdminSlot, nil)
+	if err != nil {
+		return nil, err
+	}
+	if admin != (common.Address{}) {
+		info.Admin = admin.Hex()
+	}
+
+	implementation, err := readAddressSlot(ctx, state, address, eip1967ImplementationSlot, nil)
+	if err != nil {
+		return nil, err
+	}
+	if implementation != (common.Address{}) {
+		return found("EIP1967", implementation)
+	}
+
+	beacon, err := readAddressSlot(ctx, state, address, eip1967BeaconSlot, nil)
+	if err != nil {
+		return nil, err
+	}
+	if beacon != (common.Address{}) {
+		implementation, err := callAddress(ctx, state, beacon, implementationSelector, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get implementation from beacon %s: %w", beacon.Hex(), err)
+		}
+		info.Beacon = beacon.Hex()
+		return found("EIP1967Beacon", implementation)
+	}
+
+	for _, legacy := range legacyImplementationSlots {
+		implementation, err := readAddressSlot(ctx, state, address, legacy.slot, nil)
+		if err != nil {
+			return nil, err
+		}
+		if implementation != (common.Address{}) {
+			return found(legacy.proxyType, implementation)
+		}
+	}
+
+	facets, ok, err := c.detectDiamond(ctx, state, address)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		info.IsProxy = true
+		info.ProxyType = "EIP2535"
+		info.Facets = facets
+		return info, nil
+	}
+
+	// Slot 0 is an ordinary variable in most contracts, so it is only taken as a Safe
+	// singleton when masterCopy() agrees with it
+	masterCopy, err := readAddressSlot(ctx, state, address, safeMasterCopySlot, nil)
+	if err != nil {
+		return nil, err
+	}
+	if masterCopy != (common.Address{}) {
+		if called, err := callAddress(ctx, state, address, masterCopySelector, nil); err == nil && called == masterCopy {
+			return found("GnosisSafe", masterCopy)
+		}
+	}
+
+	// EIP-897 proxies declare implementation() and proxyType(); only ask contracts whose
+	// verified ABI has both, as other contracts may have an unrelated implementation()
+	if contractABI, err := c.fetchABI(address.Hex()); err == nil {
+		_, hasImplementation := contractABI.Methods["implementation"]
+		_, hasProxyType := contractABI.Methods["proxyType"]
+		if hasImplementation && hasProxyType {
+			implementation, err := callAddress(ctx, state, address, implementationSelector, nil)
+			if err == nil && implementation != (common.Address{}) {
+				return found("EIP897", implementation)
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// minimalProxyImplementation extracts the implementation from EIP-1167 clone bytecode
+func minimalProxyImplementation(code []byte) (common.Address, bool) {
+	if len(code) != len(eip1167Prefix)+common.AddressLength+len(eip1167Suffix) ||
+		!bytes.HasPrefix(code, eip1167Prefix) || !bytes.HasSuffix(code, eip1167Suffix) {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(code[len(eip1167Prefix) : len(eip1167Prefix)+common.AddressLength]), true
+}
+
+// resolveProxyABI combines the ABI of a proxy with that of its implementation. When
+// the proxy itself has no ABI, such as an unverified proxy, the implementation ABI is used.
+func (c *Contracts) resolveProxyABI(address string, proxyABI *ContractABI, proxyErr error) (*ContractABI, error) {
+	info, err := c.GetProxyInfo(address)
+	if errors.Is(err, errNoRPCClient) {
+		// Proxies cannot be detected without reading state
+		return proxyABI, proxyErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect proxy: %w", err)
+	}
+	if !info.IsProxy {
+		return proxyABI, proxyErr
+	}
+	if info.ProxyType == "EIP2535" {
+		return c.diamondABI(address, info.Facets, proxyABI, proxyErr)
+	}
+	if len(info.Facets) > 0 {
+		// The implementation is a diamond; its facets stand in for its ABI
+		diamondABI, diamondErr := c.GetABI(info.Implementation, false)
+		implementationABI, err := c.diamondABI(info.Implementation, info.Facets, diamondABI, diamondErr)
+		if err != nil {
+			return nil, err
+		}
+		if proxyErr != nil {
+			return implementationABI, nil
+		}
+		return MergeABIs(implementationABI, proxyABI)
+	}
+
+	implementationABI, err := c.GetABI(info.Implementation, false)
+	if err != nil {
+		if proxyErr != nil {
+			return nil, fmt.Errorf("failed to get implementation ABI of proxy %s: %w", address, err)
+		}
+		return proxyABI, nil
+	}
+
+	// Definitions closer to the implementation win when several declare the same
+	// signature; proxies in between contribute their verified ABIs
+	abis := []*ContractABI{implementationABI}
+	for i := len(info.Chain) - 2; i >= 0; i-- {
+		if hopABI, err := c.GetABI(info.Chain[i], false); err == nil {
+			abis = append(abis, hopABI)
+		}
+	}
+	if proxyErr == nil {
+		abis = append(abis, proxyABI)
+	}
+	if len(abis) == 1 {
+		return implementationABI, nil
+	}
+	return MergeABIs(abis...)
+}
+
+// readAddressSlot reads an address from storage at a block, or the latest block when blockNumber is nil
+func readAddressSlot(ctx context.Context, state StateClient, address common.Address, slot common.Hash, blockNumber *big.Int) (common.Address, error) {
+	value, err := state.StorageAt(ctx, address, slot, blockNumber)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to read storage slot %s of %s: %w", slot.Hex(), address.Hex(), err)
+	}
+	return common.BytesToAddress(value), nil
+}
+
+// callAddress calls a function without arguments that returns an address
+func callAddress(ctx context.Context, state StateClient, address common.Address, selector []byte, blockNumber *big.Int) (common.Address, error) {
+	result, err := state.CallContract(ctx, ethereum.CallMsg{To: &address, Data: selector}, blockNumber)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(result) != 32 {
+		return common.Address{}, fmt.Errorf("unexpected return data %x", result)
+	}
+	return common.BytesToAddress(result), nil
+}
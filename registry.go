@@ -0,0 +1,518 @@
+package ethereal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var errNoArtifactABI = errors.New("artifact has no ABI")
+
+// ABIRegistryConfig represents the configuration of an offline ABI registry
+type ABIRegistryConfig struct {
+	ChainID int `json:"chain_id"`
+	// Dirs contain <address>.json files, optionally grouped in <chain ID>/ subdirectories
+	Dirs []string `json:"dirs"`
+	// ArtifactDirs are Foundry out/ or Hardhat artifacts/ folders
+	ArtifactDirs []string `json:"artifact_dirs"`
+	// MappingFiles maps a chain ID to a JSON file of address to ABI, artifact name or ABI file path
+	MappingFiles map[int]string `json:"mapping_files"`
+	// ReloadInterval enables hot reload when files change; zero disables it
+	ReloadInterval time.Duration `json:"reload_interval"`
+}
+
+// Artifact is a compiled contract loaded from a Foundry or Hardhat build
+type Artifact struct {
+	ContractName     string
+	SourceName       string
+	ABI              string
+	Bytecode         string
+	DeployedBytecode string
+	// LinkReferences locates library addresses in Bytecode by source and library name
+	LinkReferences map[string]map[string][]LinkReference
+}
+
+// ABIRegistry resolves ABIs from local files without any network call. It satisfies
+// EtherscanClient, so it can be handed to NewContracts or a ChainedProvider.
+type ABIRegistry struct {
+	config ABIRegistryConfig
+
+	mu        sync.RWMutex
+	byAddress map[string]string
+	artifacts map[string]*Artifact
+	// ambiguous maps contract names shared by several artifacts to their qualified names
+	ambiguous map[string][]string
+	modTimes  map[string]time.Time
+	// mapped are the ABI files the mapping file refers to, watched wherever they are
+	mapped  []string
+	lastErr error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewABIRegistry creates a new ABIRegistry and loads all configured files
+func NewABIRegistry(config ABIRegistryConfig) (*ABIRegistry, error) {
+	r := &ABIRegistry{config: config}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	if config.ReloadInterval > 0 {
+		r.stop = make(chan struct{})
+		r.done = make(chan struct{})
+		go r.watch()
+	}
+
+	return r, nil
+}
+
+// GetContractABI gets the ABI registered for a given address
+func (r *ABIRegistry) GetContractABI(address string) (string, error) {
+	if address == "" {
+		return "", errors.New("address cannot be empty")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	abi, ok := r.byAddress[strings.ToLower(address)]
+	if !ok {
+		return "", fmt.Errorf("no local ABI for %s on chain %d", address, r.config.ChainID)
+	}
+	return abi, nil
+}
+
+// GetContractSource always fails since build artifacts do not carry source code
+func (r *ABIRegistry) GetContractSource(address string) (string, error) {
+	return "", fmt.Errorf("source code for %s is not available offline", address)
+}
+
+// GetArtifact gets a build artifact by contract name or fully qualified "Source.sol:Name".
+// Names shared by contracts of several sources need the fully qualified form.
+func (r *ABIRegistry) GetArtifact(name string) (*Artifact, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if qualified, ok := r.ambiguous[name]; ok {
+		return nil, ambiguousArtifactError(name, qualified)
+	}
+	artifact, ok := r.artifacts[name]
+	if !ok {
+		return nil, fmt.Errorf("artifact %s not found", name)
+	}
+	return artifact, nil
+}
+
+// LastError returns the error of the most recent hot reload, if it failed.
+// A failed reload keeps the previously loaded ABIs.
+func (r *ABIRegistry) LastError() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastErr
+}
+
+// Close stops hot reloading
+func (r *ABIRegistry) Close() {
+	if r.stop != nil {
+		close(r.stop)
+		<-r.done
+		r.stop = nil
+	}
+}
+
+// Reload reads all configured files again, replacing the registry contents
+func (r *ABIRegistry) Reload() error {
+	modTimes, err := r.scan(nil)
+	if err != nil {
+		return err
+	}
+
+	artifacts := make(map[string]*Artifact)
+	ambiguous := make(map[string][]string)
+	for _, dir := range r.config.ArtifactDirs {
+		if err := loadArtifactDir(dir, artifacts, ambiguous); err != nil {
+			return err
+		}
+	}
+	for name := range ambiguous {
+		delete(artifacts, name)
+		sort.Strings(ambiguous[name])
+	}
+
+	byAddress := make(map[string]string)
+	for _, dir := range r.config.Dirs {
+		if err := loadABIDir(dir, r.config.ChainID, byAddress); err != nil {
+			return err
+		}
+	}
+
+	var mapped []string
+	if path, ok := r.config.MappingFiles[r.config.ChainID]; ok {
+		if mapped, err = loadMappingFile(path, artifacts, ambiguous, byAddress, modTimes); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	r.byAddress = byAddress
+	r.artifacts = artifacts
+	r.ambiguous = ambiguous
+	r.modTimes = modTimes
+	r.mapped = mapped
+	r.lastErr = nil
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *ABIRegistry) watch() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.config.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				r.mu.Lock()
+				r.lastErr = err
+				r.mu.Unlock()
+			}
+		}
+	}
+}
+
+// changed reports whether any watched file was added, removed or modified since the last load
+func (r *ABIRegistry) changed() bool {
+	r.mu.RLock()
+	mapped := r.mapped
+	r.mu.RUnlock()
+
+	modTimes, err := r.scan(mapped)
+	if err != nil {
+		return true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(modTimes) != len(r.modTimes) {
+		return true
+	}
+	for path, modTime := range modTimes {
+		if previous, ok := r.modTimes[path]; !ok || !previous.Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// scan gets the modification times of the files under the configured directories, the
+// mapping file and the given files it refers to
+func (r *ABIRegistry) scan(mapped []string) (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+
+	var roots []string
+	roots = append(roots, r.config.Dirs...)
+	roots = append(roots, r.config.ArtifactDirs...)
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || filepath.Ext(path) != ".json" {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			modTimes[path] = info.ModTime()
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+		}
+	}
+
+	if path, ok := r.config.MappingFiles[r.config.ChainID]; ok {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat mapping file: %w", err)
+		}
+		modTimes[path] = info.ModTime()
+	}
+
+	for _, path := range mapped {
+		if err := statFile(path, modTimes); err != nil {
+			return nil, err
+		}
+	}
+
+	return modTimes, nil
+}
+
+func statFile(path string, modTimes map[string]time.Time) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	modTimes[path] = info.ModTime()
+	return nil
+}
+
+// LoadArtifact reads a Foundry or Hardhat artifact file
+func LoadArtifact(path string) (*Artifact, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact, err := ParseArtifact(data)
+	if errors.Is(err, errNoArtifactABI) {
+		return nil, fmt.Errorf("%w: %s", errNoArtifactABI, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse artifact %s: %w", path, err)
+	}
+
+	// Foundry artifacts are laid out as out/<Source>.sol/<Name>.json without names inside
+	if artifact.ContractName == "" {
+		artifact.ContractName = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	if artifact.SourceName == "" {
+		artifact.SourceName = filepath.Base(filepath.Dir(path))
+	}
+
+	return artifact, nil
+}
+
+// ParseArtifact parses the contents of a Foundry or Hardhat artifact
+func ParseArtifact(data []byte) (*Artifact, error) {
+	var raw struct {
+		ContractName     string                                `json:"contractName"`
+		SourceName       string                                `json:"sourceName"`
+		ABI              json.RawMessage                       `json:"abi"`
+		Bytecode         json.RawMessage                       `json:"bytecode"`
+		DeployedBytecode json.RawMessage                       `json:"deployedBytecode"`
+		LinkReferences   map[string]map[string][]LinkReference `json:"linkReferences"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if !isABIArray(raw.ABI) {
+		return nil, errNoArtifactABI
+	}
+
+	bytecode, linkReferences := artifactBytecode(raw.Bytecode)
+	deployedBytecode, _ := artifactBytecode(raw.DeployedBytecode)
+	if linkReferences == nil {
+		linkReferences = raw.LinkReferences
+	}
+
+	return &Artifact{
+		ContractName:     raw.ContractName,
+		SourceName:       raw.SourceName,
+		ABI:              string(raw.ABI),
+		Bytecode:         bytecode,
+		DeployedBytecode: deployedBytecode,
+		LinkReferences:   linkReferences,
+	}, nil
+}
+
+// artifactBytecode handles Hardhat's plain hex string and Foundry's {"object": ...},
+// which carries the link references Hardhat has at the top level
+func artifactBytecode(raw json.RawMessage) (string, map[string]map[string][]LinkReference) {
+	var hex string
+	if err := json.Unmarshal(raw, &hex); err == nil {
+		return hex, nil
+	}
+	var object struct {
+		Object         string                                `json:"object"`
+		LinkReferences map[string]map[string][]LinkReference `json:"linkReferences"`
+	}
+	if err := json.Unmarshal(raw, &object); err == nil {
+		return object.Object, object.LinkReferences
+	}
+	return "", nil
+}
+
+// loadArtifactDir indexes artifacts by name and source:name, recording names that
+// contracts of several sources share as ambiguous
+func loadArtifactDir(dir string, artifacts map[string]*Artifact, ambiguous map[string][]string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Hardhat keeps full compiler input and output here, not artifacts
+			if d.Name() == "build-info" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".json" || strings.HasSuffix(path, ".dbg.json") {
+			return nil
+		}
+
+		artifact, err := LoadArtifact(path)
+		if errors.Is(err, errNoArtifactABI) {
+			// Other build output such as cache files lives next to artifacts
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		qualified := artifact.SourceName + ":" + artifact.ContractName
+		if _, ok := artifacts[qualified]; !ok {
+			if existing, ok := artifacts[artifact.ContractName]; ok {
+				if _, ok := ambiguous[artifact.ContractName]; !ok {
+					ambiguous[artifact.ContractName] = []string{existing.SourceName + ":" + existing.ContractName}
+				}
+				ambiguous[artifact.ContractName] = append(ambiguous[artifact.ContractName], qualified)
+			}
+		}
+		artifacts[artifact.ContractName] = artifact
+		artifacts[qualified] = artifact
+		return nil
+	})
+}
+
+func loadABIDir(dir string, chainID int, byAddress map[string]string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read ABI directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			if entry.Name() == strconv.Itoa(chainID) {
+				if err := loadABIDir(path, chainID, byAddress); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		if name == entry.Name() || !common.IsHexAddress(name) {
+			continue
+		}
+
+		abi, err := loadABIFile(path)
+		if err != nil {
+			return err
+		}
+		byAddress[strings.ToLower(name)] = abi
+	}
+
+	return nil
+}
+
+// loadMappingFile reads {"<address>": <ABI array> | "<artifact name>" | "<path to ABI file>"}
+// and returns the ABI files it refers to, adding their modification times to modTimes
+func loadMappingFile(path string, artifacts map[string]*Artifact, ambiguous map[string][]string, byAddress map[string]string, modTimes map[string]time.Time) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping file: %w", err)
+	}
+
+	var mapping map[string]json.RawMessage
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping file %s: %w", path, err)
+	}
+
+	var mapped []string
+	for address, value := range mapping {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("invalid address %q in mapping file %s", address, path)
+		}
+
+		if isABIArray(value) {
+			byAddress[strings.ToLower(address)] = string(value)
+			continue
+		}
+
+		var ref string
+		if err := json.Unmarshal(value, &ref); err != nil {
+			return nil, fmt.Errorf("invalid mapping for %s in %s", address, path)
+		}
+
+		if qualified, ok := ambiguous[ref]; ok {
+			return nil, fmt.Errorf("mapping for %s: %w", address, ambiguousArtifactError(ref, qualified))
+		}
+		if artifact, ok := artifacts[ref]; ok {
+			byAddress[strings.ToLower(address)] = artifact.ABI
+			continue
+		}
+
+		if filepath.Ext(ref) != ".json" {
+			return nil, fmt.Errorf("artifact %s mapped to %s not found", ref, address)
+		}
+		if !filepath.IsAbs(ref) {
+			ref = filepath.Join(filepath.Dir(path), ref)
+		}
+		// Stat before reading, so that a change while reading is seen by the next scan
+		if _, ok := modTimes[ref]; !ok {
+			if err := statFile(ref, modTimes); err != nil {
+				return nil, err
+			}
+			mapped = append(mapped, ref)
+		}
+		abi, err := loadABIFile(ref)
+		if err != nil {
+			return nil, err
+		}
+		byAddress[strings.ToLower(address)] = abi
+	}
+
+	return mapped, nil
+}
+
+func ambiguousArtifactError(name string, qualified []string) error {
+	return fmt.Errorf("artifact name %s is ambiguous, use one of %s", name, strings.Join(qualified, ", "))
+}
+
+// loadABIFile reads a bare ABI array or any JSON object with an "abi" field
+func loadABIFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	if isABIArray(data) {
+		return string(bytes.TrimSpace(data)), nil
+	}
+
+	var wrapped struct {
+		ABI json.RawMessage `json:"abi"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil || !isABIArray(wrapped.ABI) {
+		return "", fmt.Errorf("%s does not contain an ABI", path)
+	}
+	return string(wrapped.ABI), nil
+}
+
+func isABIArray(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '[' && json.Valid(data)
+}
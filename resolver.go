@@ -0,0 +1,133 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// HeaderClient interface defines the methods required to read block headers
+type HeaderClient interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// RPCBlockResolver converts timestamps to block numbers by searching block headers,
+// for chains without an explorer or to save explorer API quota
+type RPCBlockResolver struct {
+	client HeaderClient
+	cache  CacheClient
+}
+
+// NewRPCBlockResolver creates a new RPCBlockResolver
+func NewRPCBlockResolver(client HeaderClient, cache CacheClient) *RPCBlockResolver {
+	return &RPCBlockResolver{
+		client: client,
+		cache:  cache,
+	}
+}
+
+// GetBlockByTimestamp gets the last block at or before the timestamp when closest is
+// "before", or the first block at or after it when closest is "after" (the default)
+func (r *RPCBlockResolver) GetBlockByTimestamp(timestamp int64, closest string) (int64, error) {
+	if closest == "" {
+		closest = "after"
+	}
+	if closest != "before" && closest != "after" {
+		return 0, fmt.Errorf("closest must be \"before\" or \"after\", got %q", closest)
+	}
+
+	ctx := context.Background()
+	latest, err := r.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	hi := latest.Number.Int64()
+	hiTime := int64(latest.Time)
+
+	lo := int64(0)
+	loTime, err := r.blockTime(ctx, lo)
+	if err != nil {
+		return 0, err
+	}
+
+	// before searches for the last block at or before the timestamp, after for the block
+	// following the last one strictly before it, the first of any sharing the timestamp
+	below := func(blockTime int64) bool {
+		if closest == "before" {
+			return blockTime <= timestamp
+		}
+		return blockTime < timestamp
+	}
+
+	if !below(loTime) {
+		if closest == "before" {
+			return 0, fmt.Errorf("no block before %d, the chain starts at %d", timestamp, loTime)
+		}
+		return 0, nil
+	}
+	if below(hiTime) {
+		if closest == "after" {
+			return 0, fmt.Errorf("no block after %d yet, the latest block %d is at %d", timestamp, hi, hiTime)
+		}
+		return hi, nil
+	}
+
+	// Keep below(lo) and !below(hi). Block times are roughly regular, so interpolating usually
+	// lands within a few blocks; when it fails to halve the range the next guess bisects.
+	bisect := false
+	for hi-lo > 1 {
+		var guess int64
+		if bisect {
+			guess = lo + (hi-lo)/2
+		} else {
+			guess = lo + (timestamp-loTime)*(hi-lo)/(hiTime-loTime)
+		}
+		if guess <= lo {
+			guess = lo + 1
+		}
+		if guess >= hi {
+			guess = hi - 1
+		}
+
+		guessTime, err := r.blockTime(ctx, guess)
+		if err != nil {
+			return 0, err
+		}
+
+		span := hi - lo
+		if below(guessTime) {
+			lo, loTime = guess, guessTime
+		} else {
+			hi, hiTime = guess, guessTime
+		}
+		bisect = hi-lo > span/2
+	}
+
+	if closest == "after" {
+		return hi, nil
+	}
+	return lo, nil
+}
+
+// blockTime gets a block's timestamp, sharing the cache entries used for decoded events
+func (r *RPCBlockResolver) blockTime(ctx context.Context, number int64) (int64, error) {
+	cacheKey := fmt.Sprintf("block_timestamp_%d", number)
+	if cached, err := r.cache.Get(cacheKey); err == nil {
+		return cached.(time.Time).Unix(), nil
+	}
+
+	header, err := r.client.HeaderByNumber(ctx, big.NewInt(number))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get block %d: %w", number, err)
+	}
+	if header == nil {
+		return 0, errors.New("block not found")
+	}
+
+	r.cache.Set(cacheKey, time.Unix(int64(header.Time), 0).UTC())
+	return int64(header.Time), nil
+}
@@ -0,0 +1,197 @@
+package ethereal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// Selectors of Error(string) and Panic(uint256), which Solidity reverts with
+	errorSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+	panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+)
+
+// Panic codes as documented in Solidity's control structures reference
+var panicReasons = map[uint64]string{
+	0x00: "generic compiler panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid storage byte array encoding",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to an uninitialized function",
+}
+
+// RevertError is a decoded revert of a contract call. Use errors.As to get it from
+// errors returned by Contracts and Accounts.
+type RevertError struct {
+	// Name is "Error", "Panic" or the custom error name; empty when not recognized
+	Name      string
+	Signature string
+	// Reason is the Error(string) message, the panic description or the formatted custom error
+	Reason string
+	// PanicCode is set for Panic(uint256)
+	PanicCode *big.Int
+	// Args holds the arguments of a custom error by name
+	Args map[string]interface{}
+	// Data is the raw revert data
+	Data []byte
+
+	err error
+}
+
+// Error returns the revert reason
+func (e *RevertError) Error() string {
+	return "execution reverted: " + e.Reason
+}
+
+// Unwrap returns the RPC error the revert was decoded from, if any
+func (e *RevertError) Unwrap() error {
+	return e.err
+}
+
+// DecodeRevert decodes revert data as Error(string), Panic(uint256) or a custom error
+// defined in one of the given ABIs
+func DecodeRevert(data []byte, abis ...*ContractABI) *RevertError {
+	revert := &RevertError{Data: data}
+
+	switch {
+	case len(data) == 0:
+		revert.Reason = "no reason given"
+		return revert
+	case len(data) < 4:
+		revert.Reason = fmt.Sprintf("malformed revert data %s", hexutil.Encode(data))
+		return revert
+	case bytes.Equal(data[:4], errorSelector):
+		if reason, err := abi.UnpackRevert(data); err == nil {
+			revert.Name = "Error"
+			revert.Signature = "Error(string)"
+			revert.Reason = reason
+			return revert
+		}
+	case bytes.Equal(data[:4], panicSelector) && len(data) == 36:
+		code := new(big.Int).SetBytes(data[4:])
+		revert.Name = "Panic"
+		revert.Signature = "Panic(uint256)"
+		revert.PanicCode = code
+		revert.Reason = panicReason(code)
+		return revert
+	}
+
+	for _, contractABI := range abis {
+		if contractABI == nil {
+			continue
+		}
+		abiErr, err := contractABI.ErrorByID([4]byte(data[:4]))
+		if err != nil {
+			continue
+		}
+		args := make(map[string]interface{})
+		if err := namedArguments(abiErr.Inputs).UnpackIntoMap(args, data[4:]); err != nil {
+			continue
+		}
+		revert.Name = abiErr.Name
+		revert.Signature = abiErr.Sig
+		revert.Args = args
+		revert.Reason = formatCustomError(abiErr, args)
+		return revert
+	}
+
+	revert.Reason = fmt.Sprintf("unknown error %s", hexutil.Encode(data[:4]))
+	return revert
+}
+
+func panicReason(code *big.Int) string {
+	if code.IsUint64() {
+		if reason, ok := panicReasons[code.Uint64()]; ok {
+			return fmt.Sprintf("panic: %s (0x%x)", reason, code)
+		}
+	}
+	return fmt.Sprintf("panic: unknown code 0x%x", code)
+}
+
+// formatCustomError renders InsufficientBalance(available: 1, required: 5)
+func formatCustomError(abiErr *abi.Error, args map[string]interface{}) string {
+	inputs := namedArguments(abiErr.Inputs)
+	parts := make([]string, len(inputs))
+	for i, input := range inputs {
+		parts[i] = fmt.Sprintf("%s: %v", input.Name, formatValue(args[input.Name]))
+	}
+	return fmt.Sprintf("%s(%s)", abiErr.Name, strings.Join(parts, ", "))
+}
+
+func formatValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return hexutil.Encode(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return value
+}
+
+// revertData extracts revert data from an RPC error
+func revertData(err error) ([]byte, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+	switch data := dataErr.ErrorData().(type) {
+	case string:
+		decoded, decodeErr := hexutil.Decode(data)
+		return decoded, decodeErr == nil
+	case []byte:
+		return data, true
+	}
+	return nil, false
+}
+
+// revertError replaces an RPC error carrying revert data with a RevertError wrapping it
+func revertError(err error, abis ...*ContractABI) error {
+	if err == nil {
+		return nil
+	}
+	data, ok := revertData(err)
+	if !ok {
+		return err
+	}
+	revert := DecodeRevert(data, abis...)
+	revert.err = err
+	return revert
+}
+
+// DecodeRevert turns an error returned by eth_call or eth_estimateGas into a
+// RevertError, decoding custom errors with the ABIs of the given contracts. Errors
+// without revert data are returned unchanged.
+func (c *Contracts) DecodeRevert(err error, addresses ...string) error {
+	if _, ok := revertData(err); !ok {
+		return err
+	}
+	return revertError(err, c.errorABIs(addresses...)...)
+}
+
+// errorABIs gets the ABIs of the contracts involved in a call, skipping unknown ones
+func (c *Contracts) errorABIs(addresses ...string) []*ContractABI {
+	seen := make(map[string]bool)
+	var abis []*ContractABI
+	for _, address := range addresses {
+		if address == "" || seen[strings.ToLower(address)] {
+			continue
+		}
+		seen[strings.ToLower(address)] = true
+		if contractABI, err := c.GetABI(address, true); err == nil {
+			abis = append(abis, contractABI)
+		}
+	}
+	return abis
+}
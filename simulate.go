@@ -0,0 +1,388 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RPCCaller interface defines the method required for raw JSON-RPC requests.
+// rpc.Client satisfies it, and Simulate finds it through ethclient.Client's Client().
+type RPCCaller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// AccountOverride replaces parts of an account's state for a simulation. State
+// replaces the whole storage, StateDiff only the given slots.
+type AccountOverride struct {
+	Balance   *big.Int
+	Nonce     *uint64
+	Code      []byte
+	State     map[common.Hash]common.Hash
+	StateDiff map[common.Hash]common.Hash
+}
+
+// StateOverride maps addresses to the account state they are simulated with
+type StateOverride map[string]AccountOverride
+
+// BlockOverrides replaces fields of the block a simulation runs in
+type BlockOverrides struct {
+	Number       *big.Int
+	Time         *uint64
+	GasLimit     *uint64
+	FeeRecipient string
+	PrevRandao   *common.Hash
+	BaseFee      *big.Int
+}
+
+// SimulationCall is a transaction to simulate. Method and Args are encoded as by
+// EncodeFunctionCall; without a Method, Data is sent as is. An empty To simulates a
+// contract creation.
+type SimulationCall struct {
+	From   string
+	To     string
+	Method string // function name or full signature
+	Args   []interface{}
+	Data   []byte
+	Value  *big.Int
+	Gas    uint64
+}
+
+// SimulateOpts configures a simulation
+type SimulateOpts struct {
+	// Block is the block the simulation builds on, as accepted by CallOpts; nil for latest
+	Block          interface{}
+	StateOverrides StateOverride
+	BlockOverrides *BlockOverrides
+	// ResolveProxy encodes and decodes calls through the implementation ABI of a proxy
+	ResolveProxy bool
+}
+
+// SimulationResult is the outcome of one simulated transaction
+type SimulationResult struct {
+	// Result holds the decoded return data of calls with a Method
+	Result  *CallResult
+	Data    []byte // the raw return data
+	GasUsed uint64
+	// Logs are the logs emitted by the call; eth_call cannot report them
+	Logs []types.Log
+	// Err is set when the call failed; reverts are *RevertError
+	Err error
+}
+
+// Simulate runs a transaction against the state at opts.Block with state and block
+// overrides applied. It uses eth_simulateV1 where the node supports it, and otherwise
+// eth_call, estimating the gas used with eth_estimateGas. The error is only set when
+// the simulation could not be run; a failed call is reported in the result's Err.
+func (c *Contracts) Simulate(call SimulationCall, opts SimulateOpts) (*SimulationResult, error) {
+	results, err := c.simulate([]SimulationCall{call}, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+// SimulateBundle runs transactions in order in one simulated block, each seeing the
+// state changes of the ones before it. It requires eth_simulateV1.
+func (c *Contracts) SimulateBundle(calls []SimulationCall, opts SimulateOpts) ([]SimulationResult, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	return c.simulate(calls, opts, len(calls) == 1)
+}
+
+// simulatedCall is an encoded call of a simulation
+type simulatedCall struct {
+	args   map[string]interface{}
+	method *abi.Method
+	abis   []*ContractABI
+}
+
+func (c *Contracts) simulate(calls []SimulationCall, opts SimulateOpts, callFallback bool) ([]SimulationResult, error) {
+	caller, ok := c.rpcCaller()
+	if !ok {
+		return nil, errNoRPCClient
+	}
+	ctx := context.Background()
+
+	prepared := make([]simulatedCall, len(calls))
+	for i, call := range calls {
+		p, err := c.prepareSimulation(call, opts.ResolveProxy)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		prepared[i] = p
+	}
+	overrides, err := opts.StateOverrides.rpcArg()
+	if err != nil {
+		return nil, err
+	}
+	block, err := c.resolveCallBlock(ctx, opts.Block)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := c.simulateV1(ctx, caller, prepared, overrides, opts.BlockOverrides, block)
+	if err == nil || !isMethodNotFound(err) {
+		return results, err
+	}
+	if !callFallback {
+		return nil, fmt.Errorf("node does not support eth_simulateV1, which simulating several transactions requires: %w", err)
+	}
+	return c.simulateCall(ctx, caller, prepared[0], overrides, opts.BlockOverrides, block)
+}
+
+func (c *Contracts) prepareSimulation(call SimulationCall, resolveProxy bool) (simulatedCall, error) {
+	args := make(map[string]interface{})
+	if call.From != "" {
+		if !common.IsHexAddress(call.From) {
+			return simulatedCall{}, fmt.Errorf("invalid from address %s", call.From)
+		}
+		args["from"] = common.HexToAddress(call.From)
+	}
+	if call.Value != nil {
+		args["value"] = (*hexutil.Big)(call.Value)
+	}
+	if call.Gas > 0 {
+		args["gas"] = hexutil.Uint64(call.Gas)
+	}
+
+	if call.To == "" {
+		if call.Method != "" {
+			return simulatedCall{}, errors.New("contract creations take bytecode in Data, not a Method")
+		}
+		args["data"] = hexutil.Bytes(call.Data)
+		return simulatedCall{args: args}, nil
+	}
+	if !common.IsHexAddress(call.To) {
+		return simulatedCall{}, fmt.Errorf("invalid address %s", call.To)
+	}
+	args["to"] = common.HexToAddress(call.To)
+
+	if call.Method == "" {
+		args["data"] = hexutil.Bytes(call.Data)
+		return simulatedCall{args: args, abis: c.errorABIs(call.To)}, nil
+	}
+	p, err := c.prepareCall(CallRequest{Address: call.To, Method: call.Method, Args: call.Args}, resolveProxy)
+	if err != nil {
+		return simulatedCall{}, err
+	}
+	args["data"] = hexutil.Bytes(p.data)
+	return simulatedCall{args: args, method: p.method, abis: p.abis}, nil
+}
+
+// simulateV1Call is a call result of eth_simulateV1
+type simulateV1Call struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	Logs       []types.Log    `json:"logs"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Status     hexutil.Uint64 `json:"status"`
+	Error      *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	} `json:"error"`
+}
+
+// failure turns a failed call into a RevertError, or a plain error when the call
+// failed for another reason such as running out of gas
+func (s simulateV1Call) failure(abis []*ContractABI) error {
+	failure := simulationError{code: 3, message: "execution reverted", data: s.ReturnData.String()}
+	if s.Error != nil {
+		failure.code, failure.message = s.Error.Code, s.Error.Message
+		if s.Error.Data != "" {
+			failure.data = s.Error.Data
+		}
+	}
+	if failure.code != 3 && len(s.ReturnData) == 0 && (s.Error == nil || s.Error.Data == "") {
+		return errors.New(failure.message)
+	}
+	return revertError(failure, abis...)
+}
+
+func (c *Contracts) simulateV1(ctx context.Context, caller RPCCaller, calls []simulatedCall, overrides map[common.Address]interface{}, blockOverrides *BlockOverrides, block blockRef) ([]SimulationResult, error) {
+	callArgs := make([]map[string]interface{}, len(calls))
+	for i, call := range calls {
+		callArgs[i] = call.args
+	}
+	blockCall := map[string]interface{}{"calls": callArgs}
+	if len(overrides) > 0 {
+		blockCall["stateOverrides"] = overrides
+	}
+	if blockOverrides != nil {
+		blockCall["blockOverrides"] = blockOverrides.simulateArg()
+	}
+	payload := map[string]interface{}{"blockStateCalls": []interface{}{blockCall}}
+
+	var blocks []struct {
+		Calls []simulateV1Call `json:"calls"`
+	}
+	if err := caller.CallContext(ctx, &blocks, "eth_simulateV1", payload, block.rpcArg()); err != nil {
+		return nil, err
+	}
+	if len(blocks) != 1 || len(blocks[0].Calls) != len(calls) {
+		return nil, fmt.Errorf("eth_simulateV1 returned results for %d blocks instead of 1 block with %d calls", len(blocks), len(calls))
+	}
+
+	results := make([]SimulationResult, len(calls))
+	for i, out := range blocks[0].Calls {
+		results[i] = SimulationResult{Data: out.ReturnData, GasUsed: uint64(out.GasUsed), Logs: out.Logs}
+		if out.Status == 1 {
+			results[i].Result, results[i].Err = decodeSimulation(calls[i], out.ReturnData)
+			continue
+		}
+		results[i].Err = out.failure(calls[i].abis)
+	}
+	return results, nil
+}
+
+// simulateCall runs one call with eth_call, which reports neither logs nor gas used
+// and is followed by eth_estimateGas with the same overrides for the gas
+func (c *Contracts) simulateCall(ctx context.Context, caller RPCCaller, call simulatedCall, overrides map[common.Address]interface{}, blockOverrides *BlockOverrides, block blockRef) ([]SimulationResult, error) {
+	args := []interface{}{call.args, block.rpcArg()}
+	if len(overrides) > 0 || blockOverrides != nil {
+		args = append(args, overrides)
+	}
+	if blockOverrides != nil {
+		args = append(args, blockOverrides.callArg())
+	}
+
+	var output hexutil.Bytes
+	if err := caller.CallContext(ctx, &output, "eth_call", args...); err != nil {
+		if _, ok := revertData(err); !ok && !strings.Contains(strings.ToLower(err.Error()), "revert") {
+			return nil, fmt.Errorf("eth_call failed: %w", err)
+		}
+		return []SimulationResult{{Err: revertError(err, call.abis...)}}, nil
+	}
+
+	result := SimulationResult{Data: output}
+	result.Result, result.Err = decodeSimulation(call, output)
+
+	// eth_estimateGas takes no block overrides, and nodes may not take state overrides
+	// either; the gas used is left unset when it cannot be estimated
+	estimateArgs := []interface{}{call.args, block.rpcArg()}
+	if len(overrides) > 0 {
+		estimateArgs = append(estimateArgs, overrides)
+	}
+	var gas hexutil.Uint64
+	if blockOverrides == nil && caller.CallContext(ctx, &gas, "eth_estimateGas", estimateArgs...) == nil {
+		result.GasUsed = uint64(gas)
+	}
+	return []SimulationResult{result}, nil
+}
+
+func decodeSimulation(call simulatedCall, output []byte) (*CallResult, error) {
+	if call.method == nil {
+		return nil, nil
+	}
+	return decodeCallResult(call.method, output)
+}
+
+// rpcCaller finds raw JSON-RPC support on the client
+func (c *Contracts) rpcCaller() (RPCCaller, bool) {
+	if caller, ok := c.client.(RPCCaller); ok {
+		return caller, true
+	}
+	if provider, ok := c.client.(interface{ Client() *rpc.Client }); ok && provider.Client() != nil {
+		return provider.Client(), true
+	}
+	return nil, false
+}
+
+// isMethodNotFound reports whether a node rejected a JSON-RPC method as unknown
+func isMethodNotFound(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "method not found") || strings.Contains(msg, "does not exist") ||
+		strings.Contains(msg, "not supported") || strings.Contains(msg, "unsupported method")
+}
+
+// simulationError is a failed call of eth_simulateV1 as an RPC error, so that reverts
+// are decoded like those of eth_call
+type simulationError struct {
+	code    int
+	message string
+	data    string
+}
+
+func (e simulationError) Error() string          { return e.message }
+func (e simulationError) ErrorCode() int         { return e.code }
+func (e simulationError) ErrorData() interface{} { return e.data }
+
+// rpcArg encodes state overrides as eth_call and eth_simulateV1 take them
+func (s StateOverride) rpcArg() (map[common.Address]interface{}, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	overrides := make(map[common.Address]interface{}, len(s))
+	for address, override := range s {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("invalid override address %s", address)
+		}
+		if override.State != nil && override.StateDiff != nil {
+			return nil, fmt.Errorf("override of %s sets both State and StateDiff", address)
+		}
+		account := make(map[string]interface{})
+		if override.Balance != nil {
+			account["balance"] = (*hexutil.Big)(override.Balance)
+		}
+		if override.Nonce != nil {
+			account["nonce"] = hexutil.Uint64(*override.Nonce)
+		}
+		if override.Code != nil {
+			account["code"] = hexutil.Bytes(override.Code)
+		}
+		if override.State != nil {
+			account["state"] = override.State
+		}
+		if override.StateDiff != nil {
+			account["stateDiff"] = override.StateDiff
+		}
+		overrides[common.HexToAddress(address)] = account
+	}
+	return overrides, nil
+}
+
+// callArg encodes block overrides with the field names of geth's eth_call
+func (b *BlockOverrides) callArg() map[string]interface{} {
+	return b.encode("coinbase", "random", "baseFee")
+}
+
+// simulateArg encodes block overrides with the field names of eth_simulateV1
+func (b *BlockOverrides) simulateArg() map[string]interface{} {
+	return b.encode("feeRecipient", "prevRandao", "baseFeePerGas")
+}
+
+func (b *BlockOverrides) encode(feeRecipient, prevRandao, baseFee string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if b.Number != nil {
+		fields["number"] = (*hexutil.Big)(b.Number)
+	}
+	if b.Time != nil {
+		fields["time"] = hexutil.Uint64(*b.Time)
+	}
+	if b.GasLimit != nil {
+		fields["gasLimit"] = hexutil.Uint64(*b.GasLimit)
+	}
+	if b.FeeRecipient != "" {
+		fields[feeRecipient] = common.HexToAddress(b.FeeRecipient)
+	}
+	if b.PrevRandao != nil {
+		fields[prevRandao] = *b.PrevRandao
+	}
+	if b.BaseFee != nil {
+		fields[baseFee] = (*hexutil.Big)(b.BaseFee)
+	}
+	return fields
+}
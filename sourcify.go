@@ -0,0 +1,156 @@
+package ethereal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultSourcifyURL = "https://sourcify.dev/server"
+
+// Sourcify match types
+const (
+	SourcifyFullMatch    = "full"
+	SourcifyPartialMatch = "partial"
+)
+
+// SourcifyConfig represents the Sourcify configuration
+type SourcifyConfig struct {
+	ChainID int    `json:"chain_id"`
+	Timeout int    `json:"timeout"`
+	URL     string `json:"url"`
+	// FullMatchOnly rejects partial matches, whose metadata may differ from the deployed contract
+	FullMatchOnly bool `json:"full_match_only"`
+}
+
+// SourcifyMatch is a verified contract as stored by Sourcify
+type SourcifyMatch struct {
+	Status   string            // SourcifyFullMatch or SourcifyPartialMatch
+	ABI      string            // output.abi from metadata.json
+	Metadata string            // metadata.json as published by the compiler
+	Sources  map[string]string // source path to content
+}
+
+// Sourcify provides access to contracts verified on Sourcify
+type Sourcify struct {
+	config SourcifyConfig
+	cache  *Cache
+	client *http.Client
+}
+
+// NewSourcify creates a new Sourcify instance
+func NewSourcify(config SourcifyConfig, cache *Cache) *Sourcify {
+	if config.URL == "" {
+		config.URL = defaultSourcifyURL
+	}
+	return &Sourcify{
+		config: config,
+		cache:  cache,
+		client: &http.Client{Timeout: time.Duration(config.Timeout) * time.Second},
+	}
+}
+
+// GetMatch gets the full or partial match for a given address
+func (s *Sourcify) GetMatch(address string) (*SourcifyMatch, error) {
+	if address == "" {
+		return nil, errors.New("address cannot be empty")
+	}
+
+	cacheKey := fmt.Sprintf("sourcify:match:%d:%s", s.config.ChainID, strings.ToLower(address))
+	if cached, err := s.cache.Get(cacheKey); err == nil {
+		return cached.(*SourcifyMatch), nil
+	}
+
+	url := fmt.Sprintf("%s/files/any/%d/%s", strings.TrimRight(s.config.URL, "/"), s.config.ChainID, address)
+	resp, err := s.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("contract %s is not verified on Sourcify", address)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sourcify error: %s", resp.Status)
+	}
+
+	var body struct {
+		Status string `json:"status"`
+		Files  []struct {
+			Name    string `json:"name"`
+			Path    string `json:"path"`
+			Content string `json:"content"`
+		} `json:"files"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse Sourcify response: %w", err)
+	}
+
+	if s.config.FullMatchOnly && body.Status != SourcifyFullMatch {
+		return nil, fmt.Errorf("contract %s only has a %s match on Sourcify", address, body.Status)
+	}
+
+	match := &SourcifyMatch{
+		Status:  body.Status,
+		Sources: make(map[string]string),
+	}
+	for _, file := range body.Files {
+		if file.Name == "metadata.json" {
+			match.Metadata = file.Content
+			continue
+		}
+		// Source files are stored under <match dir>/sources/<original path>
+		if i := strings.Index(file.Path, "/sources/"); i >= 0 {
+			match.Sources[file.Path[i+len("/sources/"):]] = file.Content
+		}
+	}
+
+	if match.Metadata == "" {
+		return nil, fmt.Errorf("sourcify returned no metadata.json for %s", address)
+	}
+
+	var metadata struct {
+		Output struct {
+			ABI json.RawMessage `json:"abi"`
+		} `json:"output"`
+	}
+	if err := json.Unmarshal([]byte(match.Metadata), &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata.json: %w", err)
+	}
+	match.ABI = string(metadata.Output.ABI)
+
+	s.cache.Set(cacheKey, match)
+	return match, nil
+}
+
+// GetContractABI gets the ABI for a given address, satisfying EtherscanClient
+func (s *Sourcify) GetContractABI(address string) (string, error) {
+	match, err := s.GetMatch(address)
+	if err != nil {
+		return "", err
+	}
+	return match.ABI, nil
+}
+
+// GetContractSource gets the sources for a given address in Standard JSON "sources" format
+func (s *Sourcify) GetContractSource(address string) (string, error) {
+	match, err := s.GetMatch(address)
+	if err != nil {
+		return "", err
+	}
+
+	sources := make(map[string]map[string]string, len(match.Sources))
+	for path, content := range match.Sources {
+		sources[path] = map[string]string{"content": content}
+	}
+
+	encoded, err := json.Marshal(sources)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,347 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	defaultSubscriptionPollInterval = 5 * time.Second
+	defaultReorgDepth               = 64
+)
+
+// SubscriptionClient interface defines the methods required for eth_subscribe("logs").
+// ethclient.Client satisfies it, failing with rpc.ErrNotificationsUnsupported over HTTP.
+type SubscriptionClient interface {
+	SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error)
+}
+
+// SubscriptionConfig controls how event subscriptions follow the chain
+type SubscriptionConfig struct {
+	// PollInterval is the delay between polls without websocket support and between reconnects
+	PollInterval time.Duration
+	// ReorgDepth is the number of recent blocks checked again for reorganizations on every
+	// poll or reconnect, and the window in which duplicates are suppressed
+	ReorgDepth uint64
+}
+
+// EventSubscription delivers decoded events until Unsubscribe is called
+type EventSubscription struct {
+	events chan EventRecord
+	errs   chan error
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Events returns the channel of decoded events, in chain order. Events dropped by a
+// reorganization are delivered again with Removed set. The channel is closed on Unsubscribe.
+func (s *EventSubscription) Events() <-chan EventRecord {
+	return s.events
+}
+
+// Err reports errors the subscription recovers from by reconnecting or polling again,
+// and logs that failed to decode, which are skipped. Errors are dropped while a previous
+// one has not been received.
+func (s *EventSubscription) Err() <-chan error {
+	return s.errs
+}
+
+// Unsubscribe stops the subscription and waits for it to finish
+func (s *EventSubscription) Unsubscribe() {
+	s.cancel()
+	<-s.done
+}
+
+// SetSubscriptionConfig configures how SubscribeEvents follows the chain; zero values keep the defaults
+func (c *Contracts) SetSubscriptionConfig(config SubscriptionConfig) {
+	c.subscriptionConfig = config
+}
+
+// SubscribeEvents delivers the events GetEvents would return from filter.FromBlock
+// onwards, then follows new blocks through eth_subscribe when the client supports it
+// and by polling otherwise. filter.ToBlock must be nil.
+func (c *Contracts) SubscribeEvents(address string, event string, filter EventFilter, resolveProxy bool) (*EventSubscription, error) {
+	if filter.ToBlock != nil {
+		return nil, errors.New("toBlock cannot be set for a subscription")
+	}
+
+	abiEvent, query, dataFilters, err := c.prepareEventQuery(address, event, filter, resolveProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	from, _, err := c.blockRange(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	config := c.subscriptionConfig
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultSubscriptionPollInterval
+	}
+	if config.ReorgDepth == 0 {
+		config.ReorgDepth = defaultReorgDepth
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := &EventSubscription{
+		events: make(chan EventRecord),
+		errs:   make(chan error, 1),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	stream := &eventStream{
+		contracts: c,
+		event:     abiEvent,
+		query:     query,
+		filters:   dataFilters,
+		config:    config,
+		start:     from.Uint64(),
+		next:      from.Uint64(),
+		delivered: make(map[logKey]types.Log),
+		sub:       sub,
+	}
+	go stream.run(ctx)
+
+	return sub, nil
+}
+
+// logKey identifies a log within a specific block, so the same log in a reorganized
+// block is a different log
+type logKey struct {
+	block uint64
+	hash  common.Hash
+	index uint
+}
+
+func keyOf(log types.Log) logKey {
+	return logKey{block: log.BlockNumber, hash: log.BlockHash, index: log.Index}
+}
+
+// eventStream is the state of a subscription. Logs of the last ReorgDepth blocks are
+// remembered so that overlapping backfills and live notifications are only delivered
+// once, and logs that disappear from those blocks are delivered as removed.
+type eventStream struct {
+	contracts *Contracts
+	event     *abi.Event
+	query     ethereum.FilterQuery
+	filters   []argumentFilter
+	config    SubscriptionConfig
+
+	start     uint64
+	next      uint64 // the first block not yet synced
+	delivered map[logKey]types.Log
+	polling   bool
+
+	sub *EventSubscription
+}
+
+func (s *eventStream) run(ctx context.Context) {
+	defer close(s.sub.done)
+	defer close(s.sub.events)
+
+	for {
+		err := s.follow(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		s.report(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.config.PollInterval):
+		}
+	}
+}
+
+// follow catches up with the chain and keeps following it until an error occurs
+func (s *eventStream) follow(ctx context.Context) error {
+	if subscriber, ok := s.contracts.client.(SubscriptionClient); ok && !s.polling {
+		err := s.followSubscription(ctx, subscriber)
+		if !errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			return err
+		}
+		s.polling = true
+	}
+
+	for {
+		if err := s.sync(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.config.PollInterval):
+		}
+	}
+}
+
+func (s *eventStream) followSubscription(ctx context.Context, subscriber SubscriptionClient) error {
+	// Subscribe before backfilling so that no block falls between the two
+	live := make(chan types.Log, 128)
+	query := s.query
+	query.FromBlock, query.ToBlock = nil, nil
+	subscription, err := subscriber.SubscribeFilterLogs(ctx, query, live)
+	if err != nil {
+		return err
+	}
+	defer subscription.Unsubscribe()
+
+	if err := s.sync(ctx); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-subscription.Err():
+			if err == nil {
+				err = errors.New("log subscription closed")
+			}
+			return fmt.Errorf("log subscription failed: %w", err)
+		case log := <-live:
+			if err := s.handleLive(ctx, log); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *eventStream) handleLive(ctx context.Context, log types.Log) error {
+	key := keyOf(log)
+	if log.Removed {
+		if _, ok := s.delivered[key]; !ok {
+			return nil
+		}
+		return s.deliver(ctx, log)
+	}
+
+	// Older blocks were already synced, and are beyond the window that detects duplicates
+	if _, ok := s.delivered[key]; ok || log.BlockNumber+s.config.ReorgDepth < s.next {
+		return nil
+	}
+	if err := s.deliver(ctx, log); err != nil {
+		return err
+	}
+	if log.BlockNumber >= s.next {
+		s.next = log.BlockNumber + 1
+		s.prune()
+	}
+	return nil
+}
+
+// sync fetches logs from the start of the reorg window to the latest block, delivering
+// logs not seen before and removing delivered logs the chain no longer contains
+func (s *eventStream) sync(ctx context.Context) error {
+	header, err := s.contracts.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get latest block: %w", err)
+	}
+	head := header.Number.Uint64()
+
+	from := s.start
+	if s.next > s.start+s.config.ReorgDepth {
+		from = s.next - s.config.ReorgDepth
+	}
+	if from > head {
+		return nil
+	}
+
+	query := s.query
+	query.FromBlock = new(big.Int).SetUint64(from)
+	query.ToBlock = new(big.Int).SetUint64(head)
+	logs, err := s.contracts.fetchLogs(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	fetched := make(map[logKey]bool, len(logs))
+	for _, log := range logs {
+		fetched[keyOf(log)] = true
+	}
+
+	var removed []types.Log
+	for key, log := range s.delivered {
+		if key.block >= from && key.block <= head && !fetched[key] {
+			log.Removed = true
+			removed = append(removed, log)
+		}
+	}
+	sortLogs(removed)
+	// Removals are delivered latest first, undoing the chain in reverse
+	for i := len(removed) - 1; i >= 0; i-- {
+		if err := s.deliver(ctx, removed[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, log := range logs {
+		if _, ok := s.delivered[keyOf(log)]; ok {
+			continue
+		}
+		if err := s.deliver(ctx, log); err != nil {
+			return err
+		}
+	}
+
+	if head+1 > s.next {
+		s.next = head + 1
+	}
+	s.prune()
+	return nil
+}
+
+func (s *eventStream) report(err error) {
+	select {
+	case s.sub.errs <- err:
+	default:
+	}
+}
+
+// deliver decodes and sends a log, then records it as delivered or removed. A log that
+// does not decode would fail again on every retry, so it is reported and passed over.
+func (s *eventStream) deliver(ctx context.Context, log types.Log) error {
+	args, err := decodeEventLog(s.event, log)
+	if err != nil && !s.event.Anonymous {
+		s.report(fmt.Errorf("failed to decode %s in tx %s: %w", s.event.Sig, log.TxHash.Hex(), err))
+	}
+	// Logs of other events can only match when the event is anonymous
+	if err == nil && matchesArgumentFilters(args, s.filters) {
+		timestamp, err := s.contracts.blockTimestamp(log.BlockNumber)
+		if err != nil {
+			return err
+		}
+		select {
+		case s.sub.events <- eventRecord(s.event, log, args, timestamp):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if log.Removed {
+		delete(s.delivered, keyOf(log))
+	} else {
+		s.delivered[keyOf(log)] = log
+	}
+	return nil
+}
+
+// prune forgets delivered logs older than the reorg window
+func (s *eventStream) prune() {
+	for key := range s.delivered {
+		if key.block+s.config.ReorgDepth < s.next {
+			delete(s.delivered, key)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package ethereal
+
+import (
+	"testing"
+	"time"
+)
+
+const erc20ABI = `[
+	{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+		{"name":"from","type":"address","indexed":true},
+		{"name":"to","type":"address","indexed":true},
+		{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Approval","anonymous":false,"inputs":[
+		{"name":"owner","type":"address","indexed":true},
+		{"name":"spender","type":"address","indexed":true},
+		{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"function","name":"transfer","stateMutability":"nonpayable",
+		"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],
+		"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"balanceOf","stateMutability":"view",
+		"inputs":[{"name":"account","type":"address"}],
+		"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"error","name":"InsufficientBalance","inputs":[
+		{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]}
+]`
+
+func TestParseABIListings(t *testing.T) {
+	contractABI, err := ParseABI(erc20ABI)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	if contractABI.Raw != erc20ABI {
+		t.Error("Expected raw JSON to be retained")
+	}
+
+	events := contractABI.Events()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	transfer := events[1]
+	if transfer.Name != "Transfer" || transfer.Signature != "Transfer(address,address,uint256)" {
+		t.Errorf("Unexpected event %s %s", transfer.Name, transfer.Signature)
+	}
+	if transfer.Topic != "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef" {
+		t.Errorf("Unexpected Transfer topic %s", transfer.Topic)
+	}
+	if !transfer.Inputs[0].Indexed || transfer.Inputs[2].Indexed {
+		t.Error("Expected indexed flags to be preserved")
+	}
+
+	functions := contractABI.Functions()
+	if len(functions) != 2 {
+		t.Fatalf("Expected 2 functions, got %d", len(functions))
+	}
+	if functions[1].Signature != "transfer(address,uint256)" || functions[1].Selector != "0xa9059cbb" {
+		t.Errorf("Unexpected function %s %s", functions[1].Signature, functions[1].Selector)
+	}
+	if functions[0].StateMutability != "view" {
+		t.Errorf("Expected balanceOf to be view, got %s", functions[0].StateMutability)
+	}
+
+	errs := contractABI.Errors()
+	if len(errs) != 1 || errs[0].Signature != "InsufficientBalance(uint256,uint256)" {
+		t.Fatalf("Unexpected errors %+v", errs)
+	}
+	if len(errs[0].Selector) != 10 {
+		t.Errorf("Expected 4-byte selector, got %s", errs[0].Selector)
+	}
+}
+
+func TestParseABITupleComponents(t *testing.T) {
+	contractABI, err := ParseABI(`[{"type":"function","name":"submit","stateMutability":"nonpayable","inputs":[
+		{"name":"orders","type":"tuple[]","components":[
+			{"name":"maker","type":"address"},
+			{"name":"amounts","type":"uint256[2]"}]}],"outputs":[]}]`)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+
+	functions := contractABI.Functions()
+	if functions[0].Signature != "submit((address,uint256[2])[])" {
+		t.Errorf("Unexpected signature %s", functions[0].Signature)
+	}
+	components := functions[0].Inputs[0].Components
+	if len(components) != 2 || components[0].Name != "maker" || components[1].Type != "uint256[2]" {
+		t.Errorf("Unexpected components %+v", components)
+	}
+}
+
+func TestContractsListEvents(t *testing.T) {
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, nil, NewCache(time.Minute))
+
+	events, err := contracts.ListEvents("0x742d35Cc6634C0532925a3b844Bc454e4438f44e", false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 2 || events[0].Name != "Approval" || events[1].Name != "Transfer" {
+		t.Errorf("Unexpected events %+v", events)
+	}
+
+	signature, err := contracts.GetFunctionSignature("0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "transfer", false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if signature != "transfer(address,uint256)" {
+		t.Errorf("Unexpected signature %s", signature)
+	}
+}
@@ -1,9 +1,15 @@
 package ethereal
 
 import (
+	"context"
+	"math/big"
 	"testing"
 	"strings"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 func TestNewAccounts(t *testing.T) {
@@ -71,3 +77,48 @@ func TestDeriveAccount(t *testing.T) {
 		})
 	}
 }
+
+type fakeTransactionClient struct{}
+
+func (fakeTransactionClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (fakeTransactionClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return 7, nil
+}
+
+func (fakeTransactionClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return 21000, nil
+}
+
+func TestSignTransaction(t *testing.T) {
+	history := &ethereum.FeeHistory{
+		Reward:       [][]*big.Int{{big.NewInt(1e9), big.NewInt(2e9), big.NewInt(3e9)}},
+		BaseFee:      []*big.Int{big.NewInt(10e9), big.NewInt(10e9)},
+		GasUsedRatio: []float64{0.5},
+	}
+	a := NewAccounts()
+	a.SetTransactionBuilder(fakeTransactionClient{}, NewFeeOracle(nil, &fakeFeeHistory{history: history}))
+
+	account := &Account{PrivateKey: "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"}
+	raw, err := a.SignTransaction(account, "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "1000", nil)
+	assert.NoError(t, err)
+
+	tx := new(types.Transaction)
+	assert.NoError(t, tx.UnmarshalBinary(common.FromHex(raw)))
+	assert.Equal(t, uint64(7), tx.Nonce())
+	assert.Equal(t, uint64(21000), tx.Gas())
+	assert.Equal(t, big.NewInt(2e9), tx.GasTipCap())
+	assert.Equal(t, big.NewInt(22e9), tx.GasFeeCap())
+
+	sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(1)), tx)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23", sender.Hex())
+}
+
+func TestSignTransactionNotConfigured(t *testing.T) {
+	a := NewAccounts()
+	_, err := a.SignTransaction(&Account{}, "", "", nil)
+	assert.Error(t, err)
+}
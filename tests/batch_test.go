@@ -0,0 +1,252 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var multicallAddress = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+const testMulticallABI = `[{"type":"function","name":"aggregate3","stateMutability":"payable",
+	"inputs":[{"name":"calls","type":"tuple[]","components":[
+		{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],
+	"outputs":[{"name":"returnData","type":"tuple[]","components":[
+		{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]}]`
+
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// multicallClient serves calls directly, through a fake Multicall3 and in JSON-RPC batches
+type multicallClient struct {
+	*fakeStateClient
+	reverts      map[string][]byte
+	maxAggregate int // aggregates of more calls fail with out of gas
+	aggregates   []int
+	batches      []int
+	batchBlocks  []interface{}
+}
+
+func newMulticallClient(deployed bool) *multicallClient {
+	client := &multicallClient{fakeStateClient: newFakeStateClient(), reverts: make(map[string][]byte)}
+	if deployed {
+		client.code[multicallAddress] = []byte{0x60, 0x80}
+	}
+	return client
+}
+
+func (m *multicallClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if *call.To != multicallAddress {
+		if data, ok := m.reverts[callKey(*call.To, call.Data)]; ok {
+			return nil, &fakeDataError{data: hexutil.Encode(data)}
+		}
+		return m.fakeStateClient.CallContract(ctx, call, blockNumber)
+	}
+
+	multicall, err := abi.JSON(strings.NewReader(testMulticallABI))
+	if err != nil {
+		return nil, err
+	}
+	method := multicall.Methods["aggregate3"]
+	values, err := method.Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	calls := reflect.ValueOf(values[0])
+	if m.maxAggregate > 0 && calls.Len() > m.maxAggregate {
+		return nil, errors.New("out of gas: gas required exceeds allowance")
+	}
+	m.aggregates = append(m.aggregates, calls.Len())
+
+	results := make([]multicallResult, calls.Len())
+	for i := range results {
+		target := calls.Index(i).Field(0).Interface().(common.Address)
+		data := calls.Index(i).Field(2).Bytes()
+		if revert, ok := m.reverts[callKey(target, data)]; ok {
+			results[i] = multicallResult{ReturnData: revert}
+			continue
+		}
+		// Calls to accounts without code succeed without return data
+		results[i] = multicallResult{Success: true, ReturnData: m.calls[callKey(target, data)]}
+	}
+	return method.Outputs.Pack(results)
+}
+
+func (m *multicallClient) BatchCallContext(ctx context.Context, elems []rpc.BatchElem) error {
+	m.batches = append(m.batches, len(elems))
+	for i := range elems {
+		arg := elems[i].Args[0].(map[string]interface{})
+		m.batchBlocks = append(m.batchBlocks, elems[i].Args[1])
+		key := callKey(arg["to"].(common.Address), arg["data"].(hexutil.Bytes))
+		if revert, ok := m.reverts[key]; ok {
+			elems[i].Error = &fakeDataError{data: hexutil.Encode(revert)}
+			continue
+		}
+		*elems[i].Result.(*hexutil.Bytes) = m.calls[key]
+	}
+	return nil
+}
+
+// setBalance sets the result of balanceOf(holder)
+func (m *multicallClient) setBalance(holder common.Address, balance int64) {
+	data := append(common.FromHex("0x70a08231"), common.LeftPadBytes(holder.Bytes(), 32)...)
+	m.calls[callKey(common.HexToAddress(tokenAddress), data)] = common.BigToHash(big.NewInt(balance)).Bytes()
+}
+
+func holders(n int) []common.Address {
+	addresses := make([]common.Address, n)
+	for i := range addresses {
+		addresses[i] = common.BigToAddress(big.NewInt(int64(0x1000 + i)))
+	}
+	return addresses
+}
+
+func balanceRequests(addresses []common.Address) []CallRequest {
+	requests := make([]CallRequest, len(addresses))
+	for i, address := range addresses {
+		requests[i] = CallRequest{Address: tokenAddress, Method: "balanceOf", Args: []interface{}{address}}
+	}
+	return requests
+}
+
+func batchContracts(client *multicallClient) *Contracts {
+	return proxyContracts(client, mapProvider{strings.ToLower(tokenAddress): erc20ABI})
+}
+
+func checkBalances(t *testing.T, results []BatchResult, failed int) {
+	t.Helper()
+	for i, result := range results {
+		if i == failed {
+			var revert *RevertError
+			if !errors.As(result.Err, &revert) || revert.Name != "InsufficientBalance" {
+				t.Errorf("Call %d: expected InsufficientBalance revert, got %v", i, result.Err)
+			}
+			continue
+		}
+		if result.Err != nil {
+			t.Errorf("Call %d: expected no error, got %v", i, result.Err)
+			continue
+		}
+		if balance := result.Result.Values()["arg0"].(*big.Int); balance.Int64() != int64(i) {
+			t.Errorf("Call %d: expected balance %d, got %v", i, i, balance)
+		}
+	}
+}
+
+func TestCallBatchMulticall(t *testing.T) {
+	client := newMulticallClient(true)
+	addresses := holders(10)
+	for i, address := range addresses {
+		client.setBalance(address, int64(i))
+	}
+	failing := append(common.FromHex("0x70a08231"), common.LeftPadBytes(addresses[3].Bytes(), 32)...)
+	client.reverts[callKey(common.HexToAddress(tokenAddress), failing)] = packRevert(t, "InsufficientBalance(uint256,uint256)", big.NewInt(1), big.NewInt(2))
+	contracts := batchContracts(client)
+
+	requests := balanceRequests(addresses)
+	requests = append(requests, CallRequest{Address: tokenAddress, Method: "missing"})
+	results, err := contracts.CallBatch(requests, CallOpts{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 11 {
+		t.Fatalf("Expected 11 results, got %d", len(results))
+	}
+	checkBalances(t, results[:10], 3)
+	if results[10].Err == nil {
+		t.Error("Expected error for an unknown function")
+	}
+	if len(client.aggregates) != 1 || client.aggregates[0] != 10 {
+		t.Errorf("Expected one aggregate of 10 calls, got %v", client.aggregates)
+	}
+}
+
+func TestCallBatchSplits(t *testing.T) {
+	client := newMulticallClient(true)
+	addresses := holders(10)
+	for i, address := range addresses {
+		client.setBalance(address, int64(i))
+	}
+	contracts := batchContracts(client)
+
+	// balanceOf calldata takes 64 bytes and 160 bytes of encoding overhead
+	contracts.SetBatchConfig(BatchConfig{MaxMulticallData: 4 * 224})
+	results, err := contracts.CallBatch(balanceRequests(addresses), CallOpts{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	checkBalances(t, results, -1)
+	if !reflect.DeepEqual(client.aggregates, []int{4, 4, 2}) {
+		t.Errorf("Expected aggregates split by calldata size, got %v", client.aggregates)
+	}
+
+	// Aggregates the node rejects for gas are bisected
+	client.aggregates = nil
+	client.maxAggregate = 3
+	contracts.SetBatchConfig(BatchConfig{})
+	results, err = contracts.CallBatch(balanceRequests(addresses), CallOpts{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	checkBalances(t, results, -1)
+	if !reflect.DeepEqual(client.aggregates, []int{2, 3, 2, 3}) {
+		t.Errorf("Expected bisected aggregates, got %v", client.aggregates)
+	}
+}
+
+func TestCallBatchWithoutMulticall(t *testing.T) {
+	client := newMulticallClient(false)
+	addresses := holders(5)
+	for i, address := range addresses {
+		client.setBalance(address, int64(i))
+	}
+	failing := append(common.FromHex("0x70a08231"), common.LeftPadBytes(addresses[2].Bytes(), 32)...)
+	client.reverts[callKey(common.HexToAddress(tokenAddress), failing)] = packRevert(t, "InsufficientBalance(uint256,uint256)", big.NewInt(1), big.NewInt(2))
+	contracts := batchContracts(client)
+	contracts.SetBatchConfig(BatchConfig{MaxBatchSize: 2})
+
+	results, err := contracts.CallBatch(balanceRequests(addresses), CallOpts{Block: 1234})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	checkBalances(t, results, 2)
+	if len(client.aggregates) != 0 {
+		t.Errorf("Expected no aggregate3 calls, got %v", client.aggregates)
+	}
+	if !reflect.DeepEqual(client.batches, []int{2, 2, 1}) {
+		t.Errorf("Expected JSON-RPC batches of 2, got %v", client.batches)
+	}
+	if client.batchBlocks[0] != "0x4d2" {
+		t.Errorf("Expected block 0x4d2, got %v", client.batchBlocks[0])
+	}
+}
+
+func TestCallBatchFrom(t *testing.T) {
+	client := newMulticallClient(true)
+	addresses := holders(3)
+	for i, address := range addresses {
+		client.setBalance(address, int64(i))
+	}
+	contracts := batchContracts(client)
+
+	// Multicall3 would be the sender, so calls with a sender go in JSON-RPC batches
+	results, err := contracts.CallBatch(balanceRequests(addresses), CallOpts{From: addresses[0].Hex()})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	checkBalances(t, results, -1)
+	if len(client.aggregates) != 0 || len(client.batches) != 1 {
+		t.Errorf("Expected one JSON-RPC batch, got aggregates %v and batches %v", client.aggregates, client.batches)
+	}
+}
@@ -0,0 +1,119 @@
+package ethereal
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type fakeTimestampResolver struct {
+	blocks map[string]int64
+	calls  []string
+}
+
+func (f *fakeTimestampResolver) GetBlockByTimestamp(timestamp int64, closest string) (int64, error) {
+	key := time.Unix(timestamp, 0).UTC().Format(time.RFC3339) + " " + closest
+	f.calls = append(f.calls, key)
+	return f.blocks[key], nil
+}
+
+func TestGetEventsTimeRange(t *testing.T) {
+	client := &fakeChainClient{latest: 20000000}
+	resolver := &fakeTimestampResolver{blocks: map[string]int64{
+		"2024-03-01T00:00:00Z after":  19345000,
+		"2024-03-31T23:59:59Z before": 19559000,
+	}}
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, client, NewCache(time.Minute))
+	contracts.SetTimestampResolver(resolver)
+	contracts.SetLogFetchConfig(LogFetchConfig{ChunkSize: 1000000})
+
+	filter := EventFilter{
+		FromBlock: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		ToBlock:   "2024-03-31T23:59:59Z",
+	}
+	if _, err := contracts.GetEvents(tokenAddress, "Transfer", filter, false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(resolver.calls) != 2 {
+		t.Fatalf("Expected 2 timestamp lookups, got %v", resolver.calls)
+	}
+	query := client.queries[0]
+	if query.FromBlock.Int64() != 19345000 || query.ToBlock.Int64() != 19559000 {
+		t.Errorf("Unexpected block range %v-%v", query.FromBlock, query.ToBlock)
+	}
+}
+
+func TestBlockRange(t *testing.T) {
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, &fakeChainClient{latest: 500}, NewCache(time.Minute))
+
+	tests := []struct {
+		name     string
+		filter   EventFilter
+		from, to *big.Int
+	}{
+		{"open", EventFilter{}, big.NewInt(0), nil},
+		{"numbers", EventFilter{FromBlock: 10, ToBlock: uint64(20)}, big.NewInt(10), big.NewInt(20)},
+		{"strings", EventFilter{FromBlock: "0x10", ToBlock: "32"}, big.NewInt(16), big.NewInt(32)},
+		{"tags", EventFilter{FromBlock: "earliest", ToBlock: "latest"}, big.NewInt(0), nil},
+		{"big", EventFilter{FromBlock: big.NewInt(7)}, big.NewInt(7), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := contracts.blockRange(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if from.Cmp(tt.from) != 0 {
+				t.Errorf("Expected from %v, got %v", tt.from, from)
+			}
+			if (to == nil) != (tt.to == nil) || (to != nil && to.Cmp(tt.to) != 0) {
+				t.Errorf("Expected to %v, got %v", tt.to, to)
+			}
+		})
+	}
+}
+
+func TestBlockRangeErrors(t *testing.T) {
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, &fakeChainClient{}, NewCache(time.Minute))
+
+	filters := map[string]EventFilter{
+		"reversed":    {FromBlock: 20, ToBlock: 10},
+		"negative":    {FromBlock: -1},
+		"garbage":     {FromBlock: "yesterday"},
+		"pending":     {ToBlock: "pending"},
+		"no resolver": {FromBlock: time.Now()},
+	}
+	for name, filter := range filters {
+		if _, _, err := contracts.blockRange(context.Background(), filter); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestBlockRangeFinalized(t *testing.T) {
+	client := &finalizedChainClient{fakeChainClient: &fakeChainClient{}}
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, client, NewCache(time.Minute))
+
+	_, to, err := contracts.blockRange(context.Background(), EventFilter{ToBlock: "finalized"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if to.Int64() != 450 {
+		t.Errorf("Expected finalized block 450, got %v", to)
+	}
+}
+
+type finalizedChainClient struct {
+	*fakeChainClient
+}
+
+func (f *finalizedChainClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number != nil && number.Int64() == -3 {
+		return &types.Header{Number: big.NewInt(450)}, nil
+	}
+	return f.fakeChainClient.HeaderByNumber(ctx, number)
+}
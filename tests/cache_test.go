@@ -145,3 +145,28 @@ func TestCacheConcurrency(t *testing.T) {
 	<-done
 	<-done
 }
+
+func TestCacheSetPermanent(t *testing.T) {
+	cache := NewCache(50 * time.Millisecond)
+
+	if err := cache.SetPermanent("key1", "value1"); err != nil {
+		t.Errorf("Failed to set permanent cache value: %v", err)
+	}
+	cache.Set("key2", "value2")
+
+	// Wait past the TTL
+	time.Sleep(100 * time.Millisecond)
+	cache.Cleanup()
+
+	value, err := cache.Get("key1")
+	if err != nil {
+		t.Errorf("Expected permanent value to survive expiration, got error: %v", err)
+	}
+	if value != "value1" {
+		t.Errorf("Expected 'value1', got %v", value)
+	}
+
+	if _, err := cache.Get("key2"); err == nil {
+		t.Error("Expected error for expired key, got nil")
+	}
+}
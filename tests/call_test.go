@@ -0,0 +1,164 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// recordingCallClient answers eth_call with fixed return data and records the calls
+type recordingCallClient struct {
+	*fakeStateClient
+	result []byte
+	err    error
+	msgs   []ethereum.CallMsg
+	blocks []*big.Int
+	hashes []common.Hash
+}
+
+func (r *recordingCallClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if !strings.EqualFold(call.To.Hex(), tokenAddress) {
+		return r.fakeStateClient.CallContract(ctx, call, blockNumber)
+	}
+	r.msgs = append(r.msgs, call)
+	r.blocks = append(r.blocks, blockNumber)
+	return r.result, r.err
+}
+
+func (r *recordingCallClient) CallContractAtHash(ctx context.Context, call ethereum.CallMsg, blockHash common.Hash) ([]byte, error) {
+	r.msgs = append(r.msgs, call)
+	r.hashes = append(r.hashes, blockHash)
+	return r.result, r.err
+}
+
+func callingContracts(t *testing.T, result []byte) (*Contracts, *recordingCallClient) {
+	client := &recordingCallClient{fakeStateClient: newFakeStateClient(), result: result}
+	client.latest = 500
+	return proxyContracts(client, mapProvider{strings.ToLower(tokenAddress): erc20ABI}), client
+}
+
+func TestCall(t *testing.T) {
+	contracts, client := callingContracts(t, common.BigToHash(big.NewInt(1234)).Bytes())
+	owner := "0x0000000000000000000000000000000000000a11"
+
+	result, err := contracts.Call(tokenAddress, "balanceOf", CallOpts{From: owner}, owner)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Signature != "balanceOf(address)" {
+		t.Errorf("Unexpected signature %s", result.Signature)
+	}
+	if len(result.Outputs) != 1 || result.Outputs[0].Type != "uint256" {
+		t.Fatalf("Unexpected outputs %+v", result.Outputs)
+	}
+	if balance := result.Values()["arg0"].(*big.Int); balance.Int64() != 1234 {
+		t.Errorf("Expected 1234, got %v", balance)
+	}
+
+	msg := client.msgs[0]
+	if hexutil.Encode(msg.Data) != "0x70a082310000000000000000000000000000000000000000000000000000000000000a11" {
+		t.Errorf("Unexpected calldata %x", msg.Data)
+	}
+	if msg.From != common.HexToAddress(owner) {
+		t.Errorf("Expected from %s, got %s", owner, msg.From.Hex())
+	}
+	if client.blocks[0] != nil {
+		t.Errorf("Expected latest block, got %v", client.blocks[0])
+	}
+}
+
+func TestCallBlocks(t *testing.T) {
+	hash := "0x" + strings.Repeat("ab", 32)
+	tests := []struct {
+		name  string
+		block interface{}
+		want  *big.Int
+	}{
+		{"number", 12345, big.NewInt(12345)},
+		{"numeric string", "12345", big.NewInt(12345)},
+		{"pending", "pending", big.NewInt(-1)},
+		{"safe", "safe", big.NewInt(-4)},
+		{"finalized", "finalized", big.NewInt(-3)},
+		{"earliest", "earliest", big.NewInt(0)},
+		{"latest", "latest", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contracts, client := callingContracts(t, common.BigToHash(big.NewInt(1)).Bytes())
+			if _, err := contracts.Call(tokenAddress, "balanceOf", CallOpts{Block: tt.block}, tokenAddress); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			got := client.blocks[0]
+			if (got == nil) != (tt.want == nil) || (got != nil && got.Cmp(tt.want) != 0) {
+				t.Errorf("Expected block %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	contracts, client := callingContracts(t, common.BigToHash(big.NewInt(1)).Bytes())
+	if _, err := contracts.Call(tokenAddress, "balanceOf", CallOpts{Block: hash}, tokenAddress); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(client.hashes) != 1 || client.hashes[0] != common.HexToHash(hash) {
+		t.Errorf("Expected call at block hash, got %v", client.hashes)
+	}
+}
+
+func TestCallRevert(t *testing.T) {
+	contracts, client := callingContracts(t, nil)
+	data := packRevert(t, "InsufficientBalance(uint256,uint256)", big.NewInt(1), big.NewInt(5))
+	client.err = &fakeDataError{data: hexutil.Encode(data)}
+
+	_, err := contracts.Call(tokenAddress, "balanceOf", CallOpts{}, tokenAddress)
+	var revert *RevertError
+	if !errors.As(err, &revert) {
+		t.Fatalf("Expected RevertError, got %v", err)
+	}
+	if revert.Name != "InsufficientBalance" {
+		t.Errorf("Expected InsufficientBalance, got %q", revert.Name)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	contracts, _ := callingContracts(t, nil)
+	if _, err := contracts.Call(tokenAddress, "balanceOf", CallOpts{}, tokenAddress); err == nil || !strings.Contains(err.Error(), "no data") {
+		t.Errorf("Expected error for empty return data, got %v", err)
+	}
+	if _, err := contracts.Call(tokenAddress, "balanceOf", CallOpts{}); err == nil {
+		t.Error("Expected error for missing arguments")
+	}
+	if _, err := contracts.Call(tokenAddress, "balanceOf", CallOpts{Block: "pendin"}, tokenAddress); err == nil {
+		t.Error("Expected error for an invalid block")
+	}
+
+	noClient := proxyContracts(nil, mapProvider{strings.ToLower(tokenAddress): erc20ABI})
+	if _, err := noClient.Call(tokenAddress, "balanceOf", CallOpts{}, tokenAddress); err == nil {
+		t.Error("Expected error without an RPC client")
+	}
+}
+
+func TestCallResolvesProxy(t *testing.T) {
+	client := &recordingCallClient{fakeStateClient: newFakeStateClient(), result: common.BigToHash(big.NewInt(7)).Bytes()}
+	client.setSlot(tokenAddress, implementationSlot, implementationAddress)
+	contracts := proxyContracts(client, mapProvider{
+		strings.ToLower(tokenAddress): transparentProxyABI,
+		implementationAddress:         erc20ABI,
+	})
+
+	if _, err := contracts.Call(tokenAddress, "balanceOf", CallOpts{}, tokenAddress); err == nil {
+		t.Error("Expected error for an implementation function without ResolveProxy")
+	}
+	result, err := contracts.Call(tokenAddress, "balanceOf", CallOpts{ResolveProxy: true}, tokenAddress)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Values()["arg0"].(*big.Int).Int64() != 7 {
+		t.Errorf("Unexpected result %+v", result.Outputs)
+	}
+}
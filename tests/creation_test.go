@@ -0,0 +1,151 @@
+package ethereal
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestGetContractCreationBatching(t *testing.T) {
+	calls := 0
+	e := newFakeExplorer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("action") != "getcontractcreation" {
+			t.Errorf("Expected action=getcontractcreation, got %s", r.URL.Query().Get("action"))
+		}
+		calls++
+
+		addresses := strings.Split(r.URL.Query().Get("contractaddresses"), ",")
+		if len(addresses) > 5 {
+			t.Errorf("Expected at most 5 addresses per call, got %d", len(addresses))
+		}
+
+		var items []string
+		for _, address := range addresses {
+			items = append(items, fmt.Sprintf(`{"contractAddress":"%s","contractCreator":"0xcreator","txHash":"0xtx%s"}`, address, address[len(address)-1:]))
+		}
+		fmt.Fprintf(w, `{"status":"1","message":"OK","result":[%s]}`, strings.Join(items, ","))
+	})
+
+	var addresses []string
+	for i := 1; i <= 7; i++ {
+		addresses = append(addresses, fmt.Sprintf("0x000000000000000000000000000000000000000%d", i))
+	}
+
+	creations, err := e.GetContractCreation(addresses)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(creations) != 7 {
+		t.Fatalf("Expected 7 creations, got %d", len(creations))
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+	for i, creation := range creations {
+		if creation.ContractAddress != addresses[i] {
+			t.Errorf("Expected results in input order, got %s at %d", creation.ContractAddress, i)
+		}
+	}
+
+	// Creation data is cached permanently
+	if _, err := e.GetContractCreation(addresses[:3]); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected cached results without further calls, got %d calls", calls)
+	}
+}
+
+func TestGetContractCreationNoData(t *testing.T) {
+	e := newFakeExplorer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"0","message":"No data found","result":"No data found"}`))
+	})
+
+	creations, err := e.GetContractCreation([]string{"0x742d35Cc6634C0532925a3b844Bc454e4438f44e"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(creations) != 0 {
+		t.Errorf("Expected no creations, got %d", len(creations))
+	}
+}
+
+func TestGetConstructorArgumentsWithoutRPC(t *testing.T) {
+	e := newFakeExplorer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request %s", r.URL)
+	})
+	facade := NewEtherealFacade(e, nil, nil, NewCache(time.Minute))
+
+	_, err := facade.GetConstructorArguments("0x0000000000000000000000000000000000000001")
+	if err == nil || !strings.Contains(err.Error(), "no RPC client configured") {
+		t.Errorf("Expected missing RPC client error, got %v", err)
+	}
+}
+
+func TestDecodeConstructorArguments(t *testing.T) {
+	contractABI, err := abi.JSON(strings.NewReader(`[{"type":"constructor","inputs":[
+		{"name":"name","type":"string"},
+		{"name":"supply","type":"uint256"},
+		{"name":"owner","type":"address"}
+	]}]`))
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+
+	owner := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc454e4438f44e")
+	args, err := contractABI.Constructor.Inputs.Pack("Token", big.NewInt(1000), owner)
+	if err != nil {
+		t.Fatalf("Failed to pack arguments: %v", err)
+	}
+	initCode := common.FromHex("0x6080604052348015600f57600080fd5b50603f80601d6000396000f3fe")
+	input := append(initCode, args...)
+
+	decoded, err := DecodeConstructorArguments(contractABI, input)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if decoded["name"] != "Token" {
+		t.Errorf("Expected name Token, got %v", decoded["name"])
+	}
+	if decoded["supply"].(*big.Int).Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("Expected supply 1000, got %v", decoded["supply"])
+	}
+	if decoded["owner"] != owner {
+		t.Errorf("Expected owner %s, got %v", owner.Hex(), decoded["owner"])
+	}
+}
+
+func TestDecodeCreationTransaction(t *testing.T) {
+	contractABI, err := abi.JSON(strings.NewReader(`[{"type":"constructor","inputs":[{"name":"supply","type":"uint256"}]}]`))
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	args, err := contractABI.Constructor.Inputs.Pack(big.NewInt(1000))
+	if err != nil {
+		t.Fatalf("Failed to pack arguments: %v", err)
+	}
+	initCode := append(common.FromHex("0x6080604052348015600f57600080fd5b50"), args...)
+
+	created := types.NewTx(&types.LegacyTx{Data: initCode})
+	decoded, err := DecodeCreationTransaction(contractABI, created)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if decoded["supply"].(*big.Int).Int64() != 1000 {
+		t.Errorf("Expected supply 1000, got %v", decoded["supply"])
+	}
+
+	// A factory call ends with words that decode as arguments, but are not the constructor's
+	factory := common.HexToAddress("0x4e59b44847b379578588920cA78FbF26c0B4956C")
+	deployed := types.NewTx(&types.LegacyTx{To: &factory, Data: append(common.HexToHash("0x01").Bytes(), initCode...)})
+	if _, err := DecodeCreationTransaction(contractABI, deployed); err == nil || !strings.Contains(err.Error(), factory.Hex()) {
+		t.Errorf("Expected error naming the factory, got %v", err)
+	}
+}
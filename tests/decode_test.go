@@ -0,0 +1,222 @@
+package ethereal
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	routerAddress = "0x00000000000000000000000000000000000000aa"
+	safeAddress   = "0x00000000000000000000000000000000000000cc"
+)
+
+const routerABI = `[
+	{"type":"function","name":"aggregate3","stateMutability":"payable","inputs":[
+		{"name":"calls","type":"tuple[]","components":[
+			{"name":"target","type":"address"},
+			{"name":"allowFailure","type":"bool"},
+			{"name":"callData","type":"bytes"}]}],
+		"outputs":[]},
+	{"type":"function","name":"multicall","stateMutability":"payable","inputs":[
+		{"name":"deadline","type":"uint256"},{"name":"data","type":"bytes[]"}],"outputs":[]},
+	{"type":"function","name":"refund","stateMutability":"payable","inputs":[],"outputs":[]}
+]`
+
+const multiSendABI = `[
+	{"type":"function","name":"multiSend","stateMutability":"payable","inputs":[
+		{"name":"transactions","type":"bytes"}],"outputs":[]}
+]`
+
+// mapProvider serves a different ABI per address
+type mapProvider map[string]string
+
+func (m mapProvider) GetContractABI(address string) (string, error) {
+	if abi, ok := m[strings.ToLower(address)]; ok {
+		return abi, nil
+	}
+	return "", errNotVerified
+}
+
+func (m mapProvider) GetContractSource(address string) (string, error) {
+	return "", errNotVerified
+}
+
+var errNotVerified = &EtherscanError{Message: "Contract source code not verified"}
+
+func decodingContracts(client ChainClient) *Contracts {
+	return NewContracts(mapProvider{
+		strings.ToLower(tokenAddress): erc20ABI,
+		routerAddress:                 routerABI,
+		safeAddress:                   multiSendABI,
+	}, client, NewCache(time.Minute))
+}
+
+func encodeCall(t *testing.T, abiJSON string, name string, args ...interface{}) []byte {
+	t.Helper()
+	contractABI, err := ParseABI(abiJSON)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	data, err := contractABI.Pack(name, args...)
+	if err != nil {
+		t.Fatalf("Failed to encode %s: %v", name, err)
+	}
+	return data
+}
+
+func TestDecodeCalldataMulticall(t *testing.T) {
+	bob := common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	transfer := encodeCall(t, erc20ABI, "transfer", bob, big.NewInt(5))
+	balance := encodeCall(t, erc20ABI, "balanceOf", bob)
+	refund := encodeCall(t, routerABI, "refund")
+
+	type call3 struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	}
+	aggregate := encodeCall(t, routerABI, "aggregate3", []call3{
+		{Target: common.HexToAddress(tokenAddress), CallData: transfer},
+		{Target: common.HexToAddress(tokenAddress), AllowFailure: true, CallData: balance},
+		// Not calldata of any known function
+		{Target: common.HexToAddress(tokenAddress), CallData: []byte{1, 2, 3, 4, 5}},
+	})
+	input := encodeCall(t, routerABI, "multicall", big.NewInt(1700000000), [][]byte{aggregate, refund})
+
+	contracts := decodingContracts(nil)
+	decoded, err := contracts.DecodeCalldata(routerAddress, hexutil.Encode(input))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if decoded.Name != "multicall" || decoded.Signature != "multicall(uint256,bytes[])" {
+		t.Errorf("Unexpected function %s", decoded.Signature)
+	}
+	if decoded.Args()["deadline"].(*big.Int).Int64() != 1700000000 {
+		t.Errorf("Unexpected deadline %v", decoded.Args()["deadline"])
+	}
+
+	// multicall(bytes[]) calls the router itself
+	if len(decoded.Calls) != 2 || decoded.Calls[0].Name != "aggregate3" || decoded.Calls[1].Name != "refund" {
+		t.Fatalf("Unexpected nested calls %+v", decoded.Calls)
+	}
+
+	// aggregate3 calls each target
+	inner := decoded.Calls[0].Calls
+	if len(inner) != 2 {
+		t.Fatalf("Expected 2 calls in aggregate3, got %d", len(inner))
+	}
+	if inner[0].Name != "transfer" || inner[0].Target != common.HexToAddress(tokenAddress).Hex() {
+		t.Errorf("Unexpected first call %+v", inner[0])
+	}
+	if inner[0].Args()["to"] != bob || inner[0].Args()["value"].(*big.Int).Int64() != 5 {
+		t.Errorf("Unexpected transfer arguments %v", inner[0].Args())
+	}
+	if inner[1].Name != "balanceOf" {
+		t.Errorf("Unexpected second call %+v", inner[1])
+	}
+}
+
+func TestDecodeCalldataRoundTrip(t *testing.T) {
+	contracts := decodingContracts(nil)
+	input := encodeCall(t, erc20ABI, "transfer", common.HexToAddress("0x0000000000000000000000000000000000000b0b"), big.NewInt(5))
+
+	decoded, err := contracts.DecodeCalldata(tokenAddress, hexutil.Encode(input))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	encoded, err := contracts.EncodeFunctionCall(tokenAddress, decoded.FunctionCall)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if encoded.Data != hexutil.Encode(input) {
+		t.Errorf("Expected %s, got %s", hexutil.Encode(input), encoded.Data)
+	}
+}
+
+func TestDecodeCalldataMultiSend(t *testing.T) {
+	bob := common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	transfer := encodeCall(t, erc20ABI, "transfer", bob, big.NewInt(9))
+
+	var packed []byte
+	for _, data := range [][]byte{transfer, {}} {
+		packed = append(packed, 0)
+		packed = append(packed, common.HexToAddress(tokenAddress).Bytes()...)
+		packed = append(packed, common.LeftPadBytes(nil, 32)...)
+		packed = append(packed, common.LeftPadBytes(big.NewInt(int64(len(data))).Bytes(), 32)...)
+		packed = append(packed, data...)
+	}
+	input := encodeCall(t, multiSendABI, "multiSend", packed)
+
+	decoded, err := decodingContracts(nil).DecodeCalldata(safeAddress, hexutil.Encode(input))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(decoded.Calls) != 1 || decoded.Calls[0].Name != "transfer" {
+		t.Fatalf("Unexpected nested calls %+v", decoded.Calls)
+	}
+	if decoded.Calls[0].Args()["value"].(*big.Int).Int64() != 9 {
+		t.Errorf("Unexpected transfer arguments %v", decoded.Calls[0].Args())
+	}
+}
+
+type fakeTransactionChainClient struct {
+	*fakeChainClient
+	txs map[common.Hash]*types.Transaction
+}
+
+func (f *fakeTransactionChainClient) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	tx, ok := f.txs[hash]
+	if !ok {
+		return nil, false, errNotVerified
+	}
+	return tx, false, nil
+}
+
+func TestDecodeTransaction(t *testing.T) {
+	token := common.HexToAddress(tokenAddress)
+	input := encodeCall(t, erc20ABI, "balanceOf", token)
+	tx := types.NewTx(&types.LegacyTx{To: &token, Data: input})
+	creation := types.NewTx(&types.LegacyTx{Data: input})
+
+	client := &fakeTransactionChainClient{
+		fakeChainClient: &fakeChainClient{},
+		txs:             map[common.Hash]*types.Transaction{tx.Hash(): tx, creation.Hash(): creation},
+	}
+	contracts := decodingContracts(client)
+
+	decoded, err := contracts.DecodeTransaction(tx.Hash().Hex())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if decoded.Name != "balanceOf" || decoded.Target != token.Hex() {
+		t.Errorf("Unexpected decoded call %+v", decoded)
+	}
+
+	if _, err := contracts.DecodeTransaction(creation.Hash().Hex()); err == nil {
+		t.Error("Expected error for a contract creation, got nil")
+	}
+}
+
+func TestDecodeCalldataErrors(t *testing.T) {
+	contracts := decodingContracts(nil)
+
+	inputs := map[string]string{
+		"too short":        "0xa905",
+		"unknown selector": "0xdeadbeef",
+		"not hex":          "transfer",
+		"truncated":        "0xa9059cbb0000",
+	}
+	for name, input := range inputs {
+		if _, err := contracts.DecodeCalldata(tokenAddress, input); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
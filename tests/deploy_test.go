@@ -0,0 +1,171 @@
+package ethereal
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	tokenArtifactABI = `[{"type":"constructor","stateMutability":"nonpayable","inputs":[
+		{"name":"supply","type":"uint256"},{"name":"owner","type":"address"}]}]`
+	// keccak256("src/Math.sol:Math") starts with 22ef75b3
+	mathPlaceholder = "__$22ef75b31e2d998cd01172b890884772a9$__"
+	mathLibrary     = "0x00000000000000000000000000000000000000aa"
+	linkedBytecode  = "0x608000000000000000000000000000000000000000aa6000"
+)
+
+var deployerAddress = common.HexToAddress("0x4e59b44847b379578588920cA78FbF26c0B4956C")
+
+func foundryArtifact() string {
+	return `{"abi":` + tokenArtifactABI + `,"bytecode":{"object":"0x6080` + mathPlaceholder + `6000",
+		"linkReferences":{"src/Math.sol":{"Math":[{"start":2,"length":20}]}}}}`
+}
+
+func TestParseArtifactLinkReferences(t *testing.T) {
+	foundry, err := ParseArtifact([]byte(foundryArtifact()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if foundry.LinkReferences["src/Math.sol"]["Math"][0].Start != 2 {
+		t.Errorf("Unexpected Foundry artifact %+v", foundry)
+	}
+
+	hardhat, err := ParseArtifact([]byte(`{"contractName":"Token","abi":` + tokenArtifactABI + `,"bytecode":"0x6080` + mathPlaceholder + `6000",
+		"linkReferences":{"src/Math.sol":{"Math":[{"start":2,"length":20}]}}}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if hardhat.ContractName != "Token" || len(hardhat.LinkReferences["src/Math.sol"]["Math"]) != 1 {
+		t.Errorf("Unexpected Hardhat artifact %+v", hardhat)
+	}
+
+	if _, err := ParseArtifact([]byte(`{"bytecode":"0x6080"}`)); err == nil {
+		t.Error("Expected error for an artifact without ABI")
+	}
+}
+
+func TestLinkLibraries(t *testing.T) {
+	artifact, err := ParseArtifact([]byte(foundryArtifact()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, name := range []string{"Math", "src/Math.sol:Math"} {
+		linked, err := artifact.Link(map[string]string{name: mathLibrary})
+		if err != nil {
+			t.Fatalf("Expected no error linking %s, got %v", name, err)
+		}
+		if linked != linkedBytecode {
+			t.Errorf("Linking %s: expected %s, got %s", name, linkedBytecode, linked)
+		}
+	}
+	if _, err := artifact.Link(nil); err == nil || !strings.Contains(err.Error(), "src/Math.sol:Math") {
+		t.Errorf("Expected error for an unlinked library, got %v", err)
+	}
+	if _, err := artifact.Link(map[string]string{"Math": "0x1234"}); err == nil {
+		t.Error("Expected error for an invalid library address")
+	}
+
+	// Without link references the placeholders are replaced
+	artifact.LinkReferences = nil
+	if linked, err := artifact.Link(map[string]string{"src/Math.sol:Math": mathLibrary}); err != nil || linked != linkedBytecode {
+		t.Errorf("Expected %s, got %s and %v", linkedBytecode, linked, err)
+	}
+	if _, err := artifact.Link(map[string]string{"Math": mathLibrary}); err == nil || !strings.Contains(err.Error(), mathPlaceholder) {
+		t.Errorf("Expected error naming the unlinked placeholder, got %v", err)
+	}
+	artifact.Bytecode = "0x6080__Math__________________________________6000"
+	if linked, err := artifact.Link(map[string]string{"Math": mathLibrary}); err != nil || linked != linkedBytecode {
+		t.Errorf("Expected legacy placeholder to be linked to %s, got %s and %v", linkedBytecode, linked, err)
+	}
+}
+
+func TestDeploy(t *testing.T) {
+	client := newSendingClient()
+	contracts := transactingContracts(client)
+	artifact, err := ParseArtifact([]byte(foundryArtifact()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	owner := "0x0000000000000000000000000000000000000a11"
+
+	deployment, err := contracts.Deploy(&Account{PrivateKey: testPrivateKey}, artifact, []interface{}{"1000", owner}, DeployOpts{
+		Libraries: map[string]string{"Math": mathLibrary},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tx := client.sent[0]
+	if tx.To() != nil {
+		t.Errorf("Expected a contract creation, got a transaction to %s", tx.To().Hex())
+	}
+	want := linkedBytecode +
+		"00000000000000000000000000000000000000000000000000000000000003e8" +
+		"0000000000000000000000000000000000000000000000000000000000000a11"
+	if hexutil.Encode(tx.Data()) != want {
+		t.Errorf("Expected init code %s, got %x", want, tx.Data())
+	}
+	if deployment.Address != crypto.CreateAddress(deployment.From, 7) {
+		t.Errorf("Expected the CREATE address of nonce 7, got %s", deployment.Address.Hex())
+	}
+
+	if _, err := contracts.Deploy(&Account{PrivateKey: testPrivateKey}, &Artifact{ContractName: "IToken", ABI: "[]"}, nil, DeployOpts{}); err == nil {
+		t.Error("Expected error for an artifact without bytecode")
+	}
+	if _, err := contracts.Deploy(&Account{PrivateKey: testPrivateKey}, artifact, []interface{}{"1000"}, DeployOpts{Libraries: map[string]string{"Math": mathLibrary}}); err == nil {
+		t.Error("Expected error for missing constructor arguments")
+	}
+	if _, err := contracts.Deploy(&Account{PrivateKey: testPrivateKey}, artifact, []interface{}{"1000", owner}, DeployOpts{
+		TransactOpts: TransactOpts{Value: big.NewInt(1)},
+		Libraries:    map[string]string{"Math": mathLibrary},
+	}); err == nil {
+		t.Error("Expected error for value sent to a non-payable constructor")
+	}
+}
+
+func TestDeployCreate2(t *testing.T) {
+	client := newSendingClient()
+	contracts := transactingContracts(client)
+	artifact, err := ParseArtifact([]byte(`{"abi":[],"bytecode":"0x60806000"}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	salt := common.HexToHash("0x01")
+	account := &Account{PrivateKey: testPrivateKey}
+
+	if _, err := contracts.Deploy(account, artifact, nil, DeployOpts{Salt: &salt}); err == nil || !strings.Contains(err.Error(), "not deployed") {
+		t.Errorf("Expected error without the deployment proxy, got %v", err)
+	}
+
+	client.code[deployerAddress] = []byte{0x7f}
+	deployment, err := contracts.Deploy(account, artifact, nil, DeployOpts{Salt: &salt})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tx := client.sent[0]
+	if tx.To() == nil || *tx.To() != deployerAddress {
+		t.Fatalf("Expected a transaction to the deployment proxy, got %v", tx.To())
+	}
+	if !bytes.Equal(tx.Data(), append(salt.Bytes(), 0x60, 0x80, 0x60, 0x00)) {
+		t.Errorf("Expected salt followed by init code, got %x", tx.Data())
+	}
+
+	// keccak256(0xff ++ deployer ++ salt ++ keccak256(init code))[12:]
+	preimage := append([]byte{0xff}, deployerAddress.Bytes()...)
+	preimage = append(preimage, salt.Bytes()...)
+	preimage = append(preimage, crypto.Keccak256([]byte{0x60, 0x80, 0x60, 0x00})...)
+	want := common.BytesToAddress(crypto.Keccak256(preimage)[12:])
+	if deployment.Address != want || PredictCreate2Address(salt, []byte{0x60, 0x80, 0x60, 0x00}) != want {
+		t.Errorf("Expected CREATE2 address %s, got %s", want.Hex(), deployment.Address.Hex())
+	}
+
+	client.code[want] = []byte{0x60}
+	if _, err := contracts.Deploy(account, artifact, nil, DeployOpts{Salt: &salt}); err == nil || !strings.Contains(err.Error(), "already deployed") {
+		t.Errorf("Expected error for an occupied address, got %v", err)
+	}
+}
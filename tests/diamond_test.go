@@ -0,0 +1,197 @@
+package ethereal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	tokenFacetAddress = "0x00000000000000000000000000000000000000f1"
+	ownerFacetAddress = "0x00000000000000000000000000000000000000f2"
+)
+
+const ownershipFacetABI = `[
+	{"type":"function","name":"owner","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"type":"function","name":"transferOwnership","stateMutability":"nonpayable","inputs":[{"name":"newOwner","type":"address"}],"outputs":[]}
+]`
+
+type loupeFacet struct {
+	FacetAddress      common.Address
+	FunctionSelectors [][4]byte
+}
+
+// packFacets encodes a facets() result from facet addresses to function signatures
+func packFacets(t *testing.T, facets map[string][]string) []byte {
+	t.Helper()
+	loupe, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"facets","inputs":[],"outputs":[
+		{"name":"","type":"tuple[]","components":[{"name":"facetAddress","type":"address"},{"name":"functionSelectors","type":"bytes4[]"}]}]}]`))
+	if err != nil {
+		t.Fatalf("Failed to parse loupe ABI: %v", err)
+	}
+
+	var result []loupeFacet
+	for _, address := range []string{tokenFacetAddress, ownerFacetAddress} {
+		signatures, ok := facets[address]
+		if !ok {
+			continue
+		}
+		facet := loupeFacet{FacetAddress: common.HexToAddress(address)}
+		for _, signature := range signatures {
+			facet.FunctionSelectors = append(facet.FunctionSelectors, [4]byte(crypto.Keccak256([]byte(signature))[:4]))
+		}
+		result = append(result, facet)
+	}
+
+	packed, err := loupe.Methods["facets"].Outputs.Pack(result)
+	if err != nil {
+		t.Fatalf("Failed to pack facets: %v", err)
+	}
+	return packed
+}
+
+func diamondContracts(t *testing.T) (*Contracts, *fakeStateClient) {
+	client := newFakeStateClient()
+	client.latest = 10
+	client.setCall(tokenAddress, "facets()", packFacets(t, map[string][]string{
+		tokenFacetAddress: {"transfer(address,uint256)"},
+		ownerFacetAddress: {"owner()", "transferOwnership(address)"},
+	}))
+	return proxyContracts(client, mapProvider{
+		tokenFacetAddress: erc20ABI,
+		ownerFacetAddress: ownershipFacetABI,
+	}), client
+}
+
+func TestGetProxyInfoDiamond(t *testing.T) {
+	contracts, _ := diamondContracts(t)
+
+	info, err := contracts.GetProxyInfo(tokenAddress)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !info.IsProxy || info.ProxyType != "EIP2535" {
+		t.Fatalf("Expected diamond, got %+v", info)
+	}
+	if len(info.Facets) != 2 || info.Facets[1].Address != common.HexToAddress(ownerFacetAddress).Hex() {
+		t.Fatalf("Unexpected facets %+v", info.Facets)
+	}
+	if len(info.Facets[1].Selectors) != 2 || info.Facets[1].Selectors[0] != "0x8da5cb5b" {
+		t.Errorf("Unexpected selectors %v", info.Facets[1].Selectors)
+	}
+
+	if _, err := contracts.GetImplementationAddress(tokenAddress); err == nil {
+		t.Error("Expected error for the implementation of a diamond")
+	}
+}
+
+func TestGetABIDiamond(t *testing.T) {
+	contracts, _ := diamondContracts(t)
+
+	contractABI, err := contracts.GetABI(tokenAddress, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, name := range []string{"transfer", "owner", "transferOwnership"} {
+		if _, ok := contractABI.Methods[name]; !ok {
+			t.Errorf("Expected diamond ABI to have %s", name)
+		}
+	}
+	if _, ok := contractABI.Methods["balanceOf"]; ok {
+		t.Error("Expected functions not routed to a facet to be left out")
+	}
+	if _, ok := contractABI.ABI.Events["Transfer"]; !ok {
+		t.Error("Expected facet events to be kept")
+	}
+}
+
+func TestGetABIDiamondCut(t *testing.T) {
+	contracts, client := diamondContracts(t)
+
+	if _, err := contracts.GetABI(tokenAddress, true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// New blocks without DiamondCut keep the cached ABI
+	client.setCall(tokenAddress, "facets()", packFacets(t, map[string][]string{
+		tokenFacetAddress: {"transfer(address,uint256)", "balanceOf(address)"},
+	}))
+	client.addLogs(11)
+	contractABI, err := contracts.GetABI(tokenAddress, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := contractABI.Methods["balanceOf"]; ok {
+		t.Error("Expected the cached ABI without a DiamondCut")
+	}
+
+	client.addLogs(12, types.Log{
+		Address:     common.HexToAddress(tokenAddress),
+		Topics:      []common.Hash{crypto.Keccak256Hash([]byte("DiamondCut((address,uint8,bytes4[])[],address,bytes)"))},
+		BlockNumber: 12,
+	})
+	contractABI, err = contracts.GetABI(tokenAddress, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := contractABI.Methods["balanceOf"]; !ok {
+		t.Error("Expected the ABI to follow the DiamondCut")
+	}
+	if _, ok := contractABI.Methods["owner"]; ok {
+		t.Error("Expected the removed facet to be gone")
+	}
+
+	info, err := contracts.GetProxyInfo(tokenAddress)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(info.Facets) != 1 {
+		t.Errorf("Expected refreshed facets, got %+v", info.Facets)
+	}
+}
+
+func TestGetABIDiamondCutBehindProxy(t *testing.T) {
+	const diamondAddress = "0x00000000000000000000000000000000000000dd"
+	client := newFakeStateClient()
+	client.latest = 10
+	client.setSlot(tokenAddress, implementationSlot, diamondAddress)
+	client.setCall(diamondAddress, "facets()", packFacets(t, map[string][]string{
+		ownerFacetAddress: {"owner()"},
+	}))
+	contracts := proxyContracts(client, mapProvider{
+		strings.ToLower(tokenAddress): transparentProxyABI,
+		tokenFacetAddress:             erc20ABI,
+		ownerFacetAddress:             ownershipFacetABI,
+	})
+
+	if _, err := contracts.GetABI(tokenAddress, true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// DiamondCut is emitted by the diamond, not by the proxy in front of it
+	client.setCall(diamondAddress, "facets()", packFacets(t, map[string][]string{
+		tokenFacetAddress: {"balanceOf(address)"},
+		ownerFacetAddress: {"owner()"},
+	}))
+	client.addLogs(11, types.Log{
+		Address:     common.HexToAddress(diamondAddress),
+		Topics:      []common.Hash{crypto.Keccak256Hash([]byte("DiamondCut((address,uint8,bytes4[])[],address,bytes)"))},
+		BlockNumber: 11,
+	})
+	contractABI, err := contracts.GetABI(tokenAddress, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := contractABI.Methods["balanceOf"]; !ok {
+		t.Error("Expected the ABI to follow the DiamondCut of the diamond behind the proxy")
+	}
+	for _, query := range client.queries {
+		if len(query.Addresses) != 1 || query.Addresses[0] != common.HexToAddress(diamondAddress) {
+			t.Errorf("Expected DiamondCut to be queried on the diamond, got %v", query.Addresses)
+		}
+	}
+}
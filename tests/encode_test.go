@@ -0,0 +1,174 @@
+package ethereal
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const encodingABI = `[
+	{"type":"function","name":"foo","inputs":[{"name":"value","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"foo","inputs":[{"name":"value","type":"string"}],"outputs":[]},
+	{"type":"function","name":"foo","inputs":[{"name":"a","type":"uint256"},{"name":"b","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"submit","inputs":[
+		{"name":"order","type":"tuple","components":[
+			{"name":"to","type":"address"},
+			{"name":"amounts","type":"uint256[]"},
+			{"name":"items","type":"tuple[]","components":[
+				{"name":"id","type":"bytes32"},
+				{"name":"ok","type":"bool"}]}]},
+		{"name":"data","type":"bytes"},
+		{"name":"delta","type":"int8"},
+		{"name":"pair","type":"uint64[2]"}],"outputs":[]}
+]`
+
+func TestEncodeFunctionCall(t *testing.T) {
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, nil, NewCache(time.Minute))
+
+	encoded, err := contracts.EncodeFunctionCall(tokenAddress, FunctionCall{
+		Name: "transfer",
+		Inputs: []FunctionParam{
+			{Name: "to", Type: "address", Value: "0x0000000000000000000000000000000000000b0b"},
+			{Name: "amount", Type: "uint", Value: "1000"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if encoded.Selector != "0xa9059cbb" {
+		t.Errorf("Expected selector 0xa9059cbb, got %s", encoded.Selector)
+	}
+	expected := "0xa9059cbb" +
+		"0000000000000000000000000000000000000000000000000000000000000b0b" +
+		"00000000000000000000000000000000000000000000000000000000000003e8"
+	if encoded.Data != expected {
+		t.Errorf("Unexpected calldata %s", encoded.Data)
+	}
+	if encoded.Data != encoded.Selector+strings.TrimPrefix(encoded.Arguments, "0x") {
+		t.Errorf("Expected data to be selector followed by arguments")
+	}
+}
+
+func TestEncodeFunctionCallNestedTypes(t *testing.T) {
+	contractABI, err := ParseABI(encodingABI)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+
+	fromJSON, err := contractABI.EncodeFunctionCall(FunctionCall{
+		Name: "submit",
+		Inputs: []FunctionParam{
+			{Value: `{"to":"0x0000000000000000000000000000000000000b0b","amounts":[1,"2","0x3"],
+				"items":[["0x0000000000000000000000000000000000000000000000000000000000000001",true]]}`},
+			{Value: "0xdeadbeef"},
+			{Value: "-5"},
+			{Value: "[7, 8]"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	type item struct {
+		ID [32]byte
+		Ok bool
+	}
+	fromGo, err := contractABI.EncodeFunctionCall(FunctionCall{
+		Name: "submit",
+		Inputs: []FunctionParam{
+			{Value: map[string]interface{}{
+				"to":      common.HexToAddress("0x0000000000000000000000000000000000000b0b"),
+				"amounts": []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
+				"items":   []item{{ID: common.BigToHash(big.NewInt(1)), Ok: true}},
+			}},
+			{Value: []byte{0xde, 0xad, 0xbe, 0xef}},
+			{Value: int8(-5)},
+			{Value: [2]uint64{7, 8}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if fromJSON.Data != fromGo.Data {
+		t.Errorf("Expected JSON and Go inputs to encode identically:\n%s\n%s", fromJSON.Data, fromGo.Data)
+	}
+
+	// Round trip through go-ethereum's decoder
+	method := contractABI.ABI.Methods["submit"]
+	values, err := method.Inputs.Unpack(hexutil.MustDecode(fromJSON.Arguments))
+	if err != nil {
+		t.Fatalf("Failed to decode arguments: %v", err)
+	}
+	if values[2].(int8) != -5 || values[3].([2]uint64) != [2]uint64{7, 8} {
+		t.Errorf("Unexpected decoded values %v", values)
+	}
+}
+
+func TestEncodeFunctionCallOverloads(t *testing.T) {
+	contractABI, err := ParseABI(encodingABI)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		call      FunctionCall
+		signature string
+	}{
+		{"by count", FunctionCall{Name: "foo", Inputs: []FunctionParam{{Value: 1}, {Value: 2}}}, "foo(uint256,uint256)"},
+		{"by Go type", FunctionCall{Name: "foo", Inputs: []FunctionParam{{Value: 1}}}, "foo(uint256)"},
+		{"by declared type", FunctionCall{Name: "foo", Inputs: []FunctionParam{{Type: "string", Value: "1"}}}, "foo(string)"},
+		{"by signature", FunctionCall{Name: "foo(uint256)", Inputs: []FunctionParam{{Value: "1"}}}, "foo(uint256)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := contractABI.EncodeFunctionCall(tt.call)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if encoded.Signature != tt.signature {
+				t.Errorf("Expected %s, got %s", tt.signature, encoded.Signature)
+			}
+		})
+	}
+
+	_, err = contractABI.EncodeFunctionCall(FunctionCall{Name: "foo", Inputs: []FunctionParam{{Value: "1"}}})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Expected ambiguity error, got %v", err)
+	}
+}
+
+func TestEncodeFunctionCallErrors(t *testing.T) {
+	contractABI, err := ParseABI(encodingABI)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+
+	calls := map[string]FunctionCall{
+		"unknown function": {Name: "bar"},
+		"no overload":      {Name: "foo", Inputs: []FunctionParam{{Value: true}}},
+		"int8 overflow": {Name: "submit", Inputs: []FunctionParam{
+			{Value: `{"to":"0x0000000000000000000000000000000000000b0b","amounts":[],"items":[]}`},
+			{Value: "0x"}, {Value: 200}, {Value: "[1,2]"},
+		}},
+		"array length": {Name: "submit", Inputs: []FunctionParam{
+			{Value: `{"to":"0x0000000000000000000000000000000000000b0b","amounts":[],"items":[]}`},
+			{Value: "0x"}, {Value: 1}, {Value: "[1,2,3]"},
+		}},
+		"missing component": {Name: "submit", Inputs: []FunctionParam{
+			{Value: `{"to":"0x0000000000000000000000000000000000000b0b"}`},
+			{Value: "0x"}, {Value: 1}, {Value: "[1,2]"},
+		}},
+	}
+	for name, call := range calls {
+		if _, err := contractABI.EncodeFunctionCall(call); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package ethereal
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newFakeExplorer(t *testing.T, handler http.HandlerFunc) *Etherscan {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	e := NewEtherscan(EtherscanConfig{ChainID: 1, URL: server.URL, Timeout: 5}, NewCache(time.Minute))
+	e.pollInterval = 10 * time.Millisecond
+	return e
+}
+
+func TestVerifySourceCode(t *testing.T) {
+	e := newFakeExplorer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST, got %s", r.Method)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		if r.PostForm.Get("action") != "verifysourcecode" {
+			t.Errorf("Expected action=verifysourcecode, got %s", r.PostForm.Get("action"))
+		}
+		if r.PostForm.Get("codeformat") != "solidity-standard-json-input" {
+			t.Errorf("Unexpected codeformat %s", r.PostForm.Get("codeformat"))
+		}
+		if r.PostForm.Get("constructorArguements") != "0000000000000000000000000000000000000000000000000000000000000001" {
+			t.Errorf("Constructor arguments not forwarded without 0x prefix: %s", r.PostForm.Get("constructorArguements"))
+		}
+		w.Write([]byte(`{"status":"1","message":"OK","result":"abc123"}`))
+	})
+
+	guid, err := e.VerifySourceCode(VerificationRequest{
+		Address:              "0x742d35Cc6634C0532925a3b844Bc454e4438f44e",
+		StandardJSONInput:    `{"language":"Solidity","sources":{}}`,
+		ContractName:         "contracts/Token.sol:Token",
+		CompilerVersion:      "v0.8.24+commit.e11b9ed9",
+		ConstructorArguments: "0x0000000000000000000000000000000000000000000000000000000000000001",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if guid != "abc123" {
+		t.Errorf("Expected guid abc123, got %s", guid)
+	}
+}
+
+func TestVerifySourceCodeRejected(t *testing.T) {
+	e := newFakeExplorer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"0","message":"NOTOK","result":"Contract source code already verified"}`))
+	})
+
+	_, err := e.VerifySourceCode(VerificationRequest{
+		Address:           "0x742d35Cc6634C0532925a3b844Bc454e4438f44e",
+		StandardJSONInput: "{}",
+		ContractName:      "Token.sol:Token",
+		CompilerVersion:   "v0.8.24+commit.e11b9ed9",
+	})
+	var etherscanErr *EtherscanError
+	if !errors.As(err, &etherscanErr) {
+		t.Fatalf("Expected EtherscanError, got %v", err)
+	}
+	if etherscanErr.Message != "Contract source code already verified" {
+		t.Errorf("Expected the result as message, got %s", etherscanErr.Message)
+	}
+}
+
+func TestWaitForVerification(t *testing.T) {
+	polls := 0
+	e := newFakeExplorer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("action") != "checkverifystatus" {
+			t.Errorf("Expected action=checkverifystatus, got %s", r.URL.Query().Get("action"))
+		}
+		polls++
+		if polls < 3 {
+			w.Write([]byte(`{"status":"0","message":"NOTOK","result":"Pending in queue"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"1","message":"OK","result":"Pass - Verified"}`))
+	})
+
+	status, err := e.WaitForVerification("abc123", time.Second)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status.State != VerificationVerified {
+		t.Errorf("Expected state %s, got %s", VerificationVerified, status.State)
+	}
+	if polls != 3 {
+		t.Errorf("Expected 3 polls, got %d", polls)
+	}
+}
+
+func TestWaitForVerificationFailed(t *testing.T) {
+	e := newFakeExplorer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"0","message":"NOTOK","result":"Fail - Unable to verify"}`))
+	})
+
+	status, err := e.WaitForVerification("abc123", time.Second)
+	if err == nil {
+		t.Fatal("Expected error for failed verification, got nil")
+	}
+	if status.State != VerificationFailed {
+		t.Errorf("Expected state %s, got %s", VerificationFailed, status.State)
+	}
+}
+
+func TestWaitForVerificationTimeout(t *testing.T) {
+	e := newFakeExplorer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"0","message":"NOTOK","result":"Pending in queue"}`))
+	})
+
+	_, err := e.WaitForVerification("abc123", 50*time.Millisecond)
+	if !errors.Is(err, ErrVerificationTimeout) {
+		t.Errorf("Expected ErrVerificationTimeout, got %v", err)
+	}
+}
+
+func TestWaitForVerificationZeroInterval(t *testing.T) {
+	polls := 0
+	e := newFakeExplorer(t, func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		w.Write([]byte(`{"status":"0","message":"NOTOK","result":"Pending in queue"}`))
+	})
+	e.pollInterval = 0
+
+	// The default interval outlasts the timeout, so there is a single check
+	_, err := e.WaitForVerification("abc123", 50*time.Millisecond)
+	if !errors.Is(err, ErrVerificationTimeout) {
+		t.Errorf("Expected ErrVerificationTimeout, got %v", err)
+	}
+	if polls != 1 {
+		t.Errorf("Expected 1 check, got %d", polls)
+	}
+}
+
+func TestVerifyProxyContract(t *testing.T) {
+	e := newFakeExplorer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.Form.Get("action") {
+		case "verifyproxycontract":
+			if r.PostForm.Get("expectedimplementation") != "0x0000000000000000000000000000000000000001" {
+				t.Errorf("Unexpected expectedimplementation %s", r.PostForm.Get("expectedimplementation"))
+			}
+			w.Write([]byte(`{"status":"1","message":"OK","result":"proxy-guid"}`))
+		case "checkproxyverification":
+			w.Write([]byte(`{"status":"1","message":"OK","result":"The proxy's implementation contract is found and is successfully updated."}`))
+		default:
+			t.Errorf("Unexpected action %s", r.Form.Get("action"))
+		}
+	})
+
+	guid, err := e.VerifyProxyContract("0x742d35Cc6634C0532925a3b844Bc454e4438f44e", "0x0000000000000000000000000000000000000001")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	status, err := e.WaitForProxyVerification(guid, time.Second)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status.State != VerificationVerified {
+		t.Errorf("Expected state %s, got %s", VerificationVerified, status.State)
+	}
+}
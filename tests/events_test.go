@@ -0,0 +1,178 @@
+package ethereal
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const tokenAddress = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+
+type fakeChainClient struct {
+	mu      sync.Mutex
+	logs    []types.Log
+	latest  uint64
+	queries []ethereum.FilterQuery
+	headers int
+}
+
+func (f *fakeChainClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, q)
+	var logs []types.Log
+	for _, log := range f.logs {
+		if q.FromBlock != nil && log.BlockNumber < q.FromBlock.Uint64() {
+			continue
+		}
+		if q.ToBlock != nil && log.BlockNumber > q.ToBlock.Uint64() {
+			continue
+		}
+		if !matchesAddress(log, q.Addresses) || !matchesTopics(log, q.Topics) {
+			continue
+		}
+		logs = append(logs, log)
+	}
+	return logs, nil
+}
+
+func matchesAddress(log types.Log, addresses []common.Address) bool {
+	for _, address := range addresses {
+		if log.Address == address {
+			return true
+		}
+	}
+	return len(addresses) == 0
+}
+
+func matchesTopics(log types.Log, topics [][]common.Hash) bool {
+	for i, alternatives := range topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+		if i >= len(log.Topics) {
+			return false
+		}
+		matched := false
+		for _, topic := range alternatives {
+			if log.Topics[i] == topic {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fakeChainClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if number == nil {
+		number = new(big.Int).SetUint64(f.latest)
+	}
+	f.headers++
+	return &types.Header{Number: number, Time: 1700000000 + number.Uint64()*12}, nil
+}
+
+func transferLog(t *testing.T, block uint64, index uint, from, to common.Address, value int64) types.Log {
+	t.Helper()
+	contractABI, err := ParseABI(erc20ABI)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	event := contractABI.ABI.Events["Transfer"]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(value))
+	if err != nil {
+		t.Fatalf("Failed to pack data: %v", err)
+	}
+	return types.Log{
+		Address:     common.HexToAddress(tokenAddress),
+		Topics:      []common.Hash{event.ID, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:        data,
+		BlockNumber: block,
+		TxHash:      common.BigToHash(big.NewInt(int64(block))),
+		Index:       index,
+	}
+}
+
+func TestGetEvents(t *testing.T) {
+	alice := common.HexToAddress("0x0000000000000000000000000000000000000a11")
+	bob := common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	client := &fakeChainClient{logs: []types.Log{
+		transferLog(t, 100, 0, alice, bob, 5),
+		transferLog(t, 100, 1, bob, alice, 2),
+		transferLog(t, 101, 0, alice, bob, 7),
+	}}
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, client, NewCache(time.Minute))
+
+	records, err := contracts.GetEvents(tokenAddress, "Transfer", EventFilter{FromBlock: 100, ToBlock: 200}, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+
+	query := client.queries[0]
+	if query.Topics[0][0] != common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef") {
+		t.Errorf("Expected Transfer topic0 in query, got %v", query.Topics[0])
+	}
+	if query.FromBlock.Uint64() != 100 || query.ToBlock.Uint64() != 200 {
+		t.Errorf("Unexpected block range %v-%v", query.FromBlock, query.ToBlock)
+	}
+
+	record := records[1]
+	if record.Args["from"] != bob || record.Args["to"] != alice {
+		t.Errorf("Unexpected indexed args %v", record.Args)
+	}
+	if record.Args["value"].(*big.Int).Int64() != 2 {
+		t.Errorf("Expected value 2, got %v", record.Args["value"])
+	}
+	if record.BlockNumber != 100 || record.LogIndex != 1 {
+		t.Errorf("Unexpected position %d/%d", record.BlockNumber, record.LogIndex)
+	}
+	if !record.Timestamp.Equal(time.Unix(1700001200, 0)) {
+		t.Errorf("Unexpected timestamp %v", record.Timestamp)
+	}
+	if record.Signature != "Transfer(address,address,uint256)" {
+		t.Errorf("Unexpected signature %s", record.Signature)
+	}
+
+	// Headers are fetched once per block
+	if client.headers != 2 {
+		t.Errorf("Expected 2 header lookups, got %d", client.headers)
+	}
+}
+
+func TestGetEventsUnknownEvent(t *testing.T) {
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, &fakeChainClient{}, NewCache(time.Minute))
+	if _, err := contracts.GetEvents(tokenAddress, "Mint", EventFilter{}, false); err == nil {
+		t.Error("Expected error for unknown event, got nil")
+	}
+}
+
+func TestGetEventsTopicFilter(t *testing.T) {
+	client := &fakeChainClient{latest: 1000}
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, client, NewCache(time.Minute))
+
+	from := "0x0000000000000000000000000000000000000000000000000000000000000a11"
+	if _, err := contracts.GetEvents(tokenAddress, "Transfer", EventFilter{Topics: []string{from}}, false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	query := client.queries[0]
+	if query.ToBlock.Uint64() != 1000 {
+		t.Errorf("Expected open-ended query to end at the latest block, got ToBlock %v", query.ToBlock)
+	}
+	if len(query.Topics) != 2 || query.Topics[1][0] != common.HexToHash(from) {
+		t.Errorf("Expected topic1 filter, got %v", query.Topics)
+	}
+}
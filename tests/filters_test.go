@@ -0,0 +1,115 @@
+package ethereal
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestGetEventsArgumentFilters(t *testing.T) {
+	alice := common.HexToAddress("0x0000000000000000000000000000000000000a11")
+	bob := common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	carol := common.HexToAddress("0x0000000000000000000000000000000000000ca1")
+	client := &fakeChainClient{logs: []types.Log{
+		transferLog(t, 100, 0, alice, bob, 5),
+		transferLog(t, 100, 1, bob, alice, 2),
+		transferLog(t, 101, 0, carol, bob, 7),
+		transferLog(t, 102, 0, alice, carol, 7),
+	}}
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, client, NewCache(time.Minute))
+
+	filter := EventFilter{
+		FromBlock: 100,
+		ToBlock:   200,
+		ArgumentFilters: map[string]interface{}{
+			"from":  []string{alice.Hex(), strings.ToLower(carol.Hex())},
+			"value": "7",
+		},
+	}
+	records, err := contracts.GetEvents(tokenAddress, "Transfer", filter, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[0].Args["from"] != carol || records[1].Args["from"] != alice {
+		t.Errorf("Unexpected records %v", records)
+	}
+
+	query := client.queries[0]
+	if len(query.Topics) != 2 || len(query.Topics[1]) != 2 {
+		t.Fatalf("Expected 2 alternatives for topic1, got %v", query.Topics)
+	}
+	if query.Topics[1][0] != common.BytesToHash(alice.Bytes()) || query.Topics[1][1] != common.BytesToHash(carol.Bytes()) {
+		t.Errorf("Unexpected topic1 %v", query.Topics[1])
+	}
+}
+
+func TestEventQueryArgumentFilterErrors(t *testing.T) {
+	contractABI, err := ParseABI(erc20ABI)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	event := contractABI.ABI.Events["Transfer"]
+	address := common.HexToAddress(tokenAddress)
+
+	tests := []struct {
+		name    string
+		filter  EventFilter
+		message string
+	}{
+		{"unknown argument", EventFilter{ArgumentFilters: map[string]interface{}{"owner": "0x1"}}, `no argument "owner"`},
+		{"invalid address", EventFilter{ArgumentFilters: map[string]interface{}{"to": "bob"}}, "invalid address"},
+		{"invalid integer", EventFilter{ArgumentFilters: map[string]interface{}{"value": "lots"}}, "invalid integer"},
+		{"negative uint", EventFilter{ArgumentFilters: map[string]interface{}{"value": -1}}, "out of range"},
+		{"empty alternatives", EventFilter{ArgumentFilters: map[string]interface{}{"to": []string{}}}, "no values"},
+		{"topic conflict", EventFilter{
+			Topics:          []string{"0x0000000000000000000000000000000000000000000000000000000000000a11"},
+			ArgumentFilters: map[string]interface{}{"from": "0x0000000000000000000000000000000000000a11"},
+		}, "conflicts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := eventQuery(address, &event, tt.filter)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestTopicValue(t *testing.T) {
+	contractABI, err := ParseABI(`[{"type":"event","name":"Log","inputs":[
+		{"name":"id","type":"int64","indexed":true},
+		{"name":"tag","type":"string","indexed":true},
+		{"name":"key","type":"bytes4","indexed":true}]}]`)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	inputs := contractABI.ABI.Events["Log"].Inputs
+
+	hash, err := topicValue(inputs[0].Type, big.NewInt(-1))
+	if err != nil || hash != common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff") {
+		t.Errorf("Expected sign-extended topic, got %s (%v)", hash.Hex(), err)
+	}
+
+	hash, err = topicValue(inputs[1].Type, "hello")
+	if err != nil || hash != crypto.Keccak256Hash([]byte("hello")) {
+		t.Errorf("Expected hashed string topic, got %s (%v)", hash.Hex(), err)
+	}
+
+	hash, err = topicValue(inputs[2].Type, "0xa9059cbb")
+	if err != nil || hash != common.HexToHash("0xa9059cbb00000000000000000000000000000000000000000000000000000000") {
+		t.Errorf("Expected left-aligned bytes4 topic, got %s (%v)", hash.Hex(), err)
+	}
+
+	if _, err := topicValue(inputs[2].Type, "0xa9059c"); err == nil {
+		t.Error("Expected error for wrong bytes4 length, got nil")
+	}
+}
@@ -0,0 +1,137 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+type fakeFeeHistory struct {
+	history *ethereum.FeeHistory
+	err     error
+}
+
+func (f *fakeFeeHistory) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	return f.history, f.err
+}
+
+type fakeGasTracker struct {
+	oracle *GasOracle
+	err    error
+	waits  int
+}
+
+func (f *fakeGasTracker) GetGasOracle() (*GasOracle, error) {
+	return f.oracle, f.err
+}
+
+func (f *fakeGasTracker) GetConfirmationTime(gasPrice *big.Int) (time.Duration, error) {
+	f.waits++
+	return 30 * time.Second, nil
+}
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e9))
+}
+
+func TestSuggestFeesFromHistory(t *testing.T) {
+	history := &ethereum.FeeHistory{
+		Reward: [][]*big.Int{
+			{gwei(1), gwei(2), gwei(5)},
+			{big.NewInt(0), big.NewInt(0), big.NewInt(0)}, // empty block
+			{gwei(1), gwei(3), gwei(6)},
+			{gwei(2), gwei(3), gwei(7)},
+		},
+		BaseFee:      []*big.Int{gwei(10), gwei(10), gwei(11), gwei(12), gwei(20)},
+		GasUsedRatio: []float64{0.5, 0, 0.6, 0.7},
+	}
+	oracle := NewFeeOracle(nil, &fakeFeeHistory{history: history})
+
+	fees, err := oracle.SuggestFees()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fees.BaseFee.Cmp(gwei(20)) != 0 {
+		t.Errorf("Expected next block base fee 20 gwei, got %v", fees.BaseFee)
+	}
+	if fees.Standard.MaxPriorityFeePerGas.Cmp(gwei(3)) != 0 {
+		t.Errorf("Expected standard tip 3 gwei, got %v", fees.Standard.MaxPriorityFeePerGas)
+	}
+	if fees.Fast.MaxFeePerGas.Cmp(gwei(46)) != 0 {
+		t.Errorf("Expected fast max fee 46 gwei, got %v", fees.Fast.MaxFeePerGas)
+	}
+	if fees.Slow.EstimatedWait != 0 {
+		t.Errorf("Expected no wait estimate without gas tracker, got %v", fees.Slow.EstimatedWait)
+	}
+}
+
+func TestSuggestFeesCombinesSources(t *testing.T) {
+	history := &ethereum.FeeHistory{
+		Reward:       [][]*big.Int{{gwei(1), gwei(2), gwei(3)}},
+		BaseFee:      []*big.Int{gwei(10), gwei(10)},
+		GasUsedRatio: []float64{0.5},
+	}
+	tracker := &fakeGasTracker{oracle: &GasOracle{
+		SafeGasPrice:    gwei(11),
+		ProposeGasPrice: gwei(12),
+		FastGasPrice:    gwei(20),
+		SuggestBaseFee:  gwei(10),
+	}}
+	oracle := NewFeeOracle(tracker, &fakeFeeHistory{history: history})
+
+	fees, err := oracle.SuggestFees()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fees.Standard.MaxPriorityFeePerGas.Cmp(gwei(2)) != 0 {
+		t.Errorf("Expected standard tip 2 gwei, got %v", fees.Standard.MaxPriorityFeePerGas)
+	}
+	if fees.Fast.MaxPriorityFeePerGas.Cmp(gwei(10)) != 0 {
+		t.Errorf("Expected fast tip from gas tracker (10 gwei), got %v", fees.Fast.MaxPriorityFeePerGas)
+	}
+	if fees.Fast.EstimatedWait != 0 || tracker.waits != 0 {
+		t.Errorf("Expected no confirmation time requests, got %d", tracker.waits)
+	}
+
+	fees, err = oracle.SuggestFeesWithWaits()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fees.Fast.EstimatedWait != 30*time.Second || tracker.waits != 3 {
+		t.Errorf("Expected wait estimates of 30s for 3 tiers, got %v after %d requests", fees.Fast.EstimatedWait, tracker.waits)
+	}
+}
+
+func TestSuggestFeesNoSources(t *testing.T) {
+	oracle := NewFeeOracle(&fakeGasTracker{err: errors.New("down")}, &fakeFeeHistory{err: errors.New("down")})
+	if _, err := oracle.SuggestFees(); err == nil {
+		t.Error("Expected error when both sources fail, got nil")
+	}
+}
+
+func TestGetGasOracle(t *testing.T) {
+	e := newFakeExplorer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("module") != "gastracker" {
+			t.Errorf("Expected module=gastracker, got %s", r.URL.Query().Get("module"))
+		}
+		w.Write([]byte(`{"status":"1","message":"OK","result":{
+			"LastBlock":"19000000","SafeGasPrice":"12","ProposeGasPrice":"13.5",
+			"FastGasPrice":"15","suggestBaseFee":"11.123456789","gasUsedRatio":"0.5"}}`))
+	})
+
+	oracle, err := e.GetGasOracle()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if oracle.ProposeGasPrice.Cmp(big.NewInt(13500000000)) != 0 {
+		t.Errorf("Expected 13.5 gwei in wei, got %v", oracle.ProposeGasPrice)
+	}
+	if oracle.SuggestBaseFee.Cmp(big.NewInt(11123456789)) != 0 {
+		t.Errorf("Expected 11.123456789 gwei in wei, got %v", oracle.SuggestBaseFee)
+	}
+}
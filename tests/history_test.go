@@ -0,0 +1,312 @@
+package ethereal
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const upgradedImplementationAddress = "0x00000000000000000000000000000000000000d2"
+
+// tokenV2ABI changes Transfer, as an upgrade that adds an event parameter does
+const tokenV2ABI = `[
+	{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+		{"name":"from","type":"address","indexed":true},
+		{"name":"to","type":"address","indexed":true},
+		{"name":"value","type":"uint256","indexed":false},
+		{"name":"memo","type":"string","indexed":false}]}
+]`
+
+func upgradeLog(emitter string, signature string, target string, block uint64) types.Log {
+	return types.Log{
+		Address:     common.HexToAddress(emitter),
+		Topics:      []common.Hash{crypto.Keccak256Hash([]byte(signature)), common.BytesToHash(common.HexToAddress(target).Bytes())},
+		BlockNumber: block,
+	}
+}
+
+func transferV2Log(t *testing.T, block uint64, from, to common.Address, value int64, memo string) types.Log {
+	t.Helper()
+	contractABI, err := ParseABI(tokenV2ABI)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	event := contractABI.ABI.Events["Transfer"]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(value), memo)
+	if err != nil {
+		t.Fatalf("Failed to pack data: %v", err)
+	}
+	return types.Log{
+		Address:     common.HexToAddress(tokenAddress),
+		Topics:      []common.Hash{event.ID, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:        data,
+		BlockNumber: block,
+	}
+}
+
+func historyContracts(client *fakeStateClient) *Contracts {
+	return proxyContracts(client, mapProvider{
+		strings.ToLower(tokenAddress): transparentProxyABI,
+		implementationAddress:         erc20ABI,
+		upgradedImplementationAddress: tokenV2ABI,
+	})
+}
+
+func TestGetProxyHistory(t *testing.T) {
+	client := newFakeStateClient()
+	client.latest = 300
+	client.setSlot(tokenAddress, implementationSlot, implementationAddress)
+	client.logs = []types.Log{
+		upgradeLog(tokenAddress, "Upgraded(address)", upgradedImplementationAddress, 150),
+		// Upgrading to the active implementation changes nothing
+		upgradeLog(tokenAddress, "Upgraded(address)", upgradedImplementationAddress, 160),
+	}
+	contracts := historyContracts(client)
+
+	history, err := contracts.GetProxyHistory(tokenAddress, 100, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 periods, got %+v", history)
+	}
+	first, second := history[0], history[1]
+	if first.Implementation != common.HexToAddress(implementationAddress).Hex() || first.FromBlock != 100 || first.ToBlock != 149 {
+		t.Errorf("Unexpected first period %+v", first)
+	}
+	if second.Implementation != common.HexToAddress(upgradedImplementationAddress).Hex() || second.FromBlock != 150 || second.ToBlock != 300 {
+		t.Errorf("Unexpected second period %+v", second)
+	}
+
+	if client.lastBlock == nil || client.lastBlock.Uint64() != 100 {
+		t.Errorf("Expected the implementation to be read at block 100, got %v", client.lastBlock)
+	}
+}
+
+func TestGetProxyHistoryBeacon(t *testing.T) {
+	const otherBeacon = "0x00000000000000000000000000000000000000bf"
+	client := newFakeStateClient()
+	client.latest = 300
+	client.setSlot(tokenAddress, beaconSlot, beaconAddress)
+	client.setCall(beaconAddress, "implementation()", returnAddress(implementationAddress))
+	client.setCall(otherBeacon, "implementation()", returnAddress(upgradedImplementationAddress))
+	client.logs = []types.Log{
+		upgradeLog(beaconAddress, "Upgraded(address)", upgradedImplementationAddress, 120),
+		upgradeLog(beaconAddress, "Upgraded(address)", implementationAddress, 140),
+		upgradeLog(tokenAddress, "BeaconUpgraded(address)", otherBeacon, 200),
+	}
+	contracts := historyContracts(client)
+
+	history, err := contracts.GetProxyHistory(tokenAddress, 100, 250)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []ImplementationPeriod{
+		{implementationAddress, beaconAddress, 100, 119},
+		{upgradedImplementationAddress, beaconAddress, 120, 139},
+		{implementationAddress, beaconAddress, 140, 199},
+		{upgradedImplementationAddress, otherBeacon, 200, 250},
+	}
+	if len(history) != len(expected) {
+		t.Fatalf("Expected %d periods, got %+v", len(expected), history)
+	}
+	for i, period := range expected {
+		period.Implementation = common.HexToAddress(period.Implementation).Hex()
+		period.Beacon = common.HexToAddress(period.Beacon).Hex()
+		if history[i] != period {
+			t.Errorf("Period %d: expected %+v, got %+v", i, period, history[i])
+		}
+	}
+}
+
+func TestGetProxyHistoryWithoutArchive(t *testing.T) {
+	client := newFakeStateClient()
+	client.latest = 300
+	client.pruned = true
+	client.setSlot(tokenAddress, implementationSlot, upgradedImplementationAddress)
+	client.logs = []types.Log{
+		upgradeLog(tokenAddress, "Upgraded(address)", implementationAddress, 50),
+		upgradeLog(tokenAddress, "Upgraded(address)", upgradedImplementationAddress, 150),
+	}
+	contracts := historyContracts(client)
+
+	// The implementation at block 100 is the target of the upgrade at block 50
+	history, err := contracts.GetProxyHistory(tokenAddress, 100, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 2 || history[0].Implementation != common.HexToAddress(implementationAddress).Hex() || history[0].FromBlock != 100 {
+		t.Errorf("Expected the implementation of the earlier upgrade, got %+v", history)
+	}
+
+	// Before the first upgrade the state cannot be read, and the current implementation is assumed
+	history, err = contracts.GetProxyHistory(tokenAddress, 0, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 3 || history[0].Implementation != common.HexToAddress(upgradedImplementationAddress).Hex() || history[0].ToBlock != 49 {
+		t.Errorf("Expected the current implementation before the first upgrade, got %+v", history)
+	}
+}
+
+func TestGetProxyHistoryFromCreation(t *testing.T) {
+	client := newFakeStateClient()
+	client.latest = 300
+	proxy := common.HexToAddress(tokenAddress)
+	client.code[proxy] = []byte{0x60, 0x80}
+	client.deployed[proxy] = 80
+	client.setSlot(tokenAddress, implementationSlot, implementationAddress)
+	client.logs = []types.Log{
+		upgradeLog(tokenAddress, "Upgraded(address)", upgradedImplementationAddress, 150),
+	}
+	contracts := historyContracts(client)
+
+	history, err := contracts.GetProxyHistory(tokenAddress, 100, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 periods, got %+v", history)
+	}
+	if len(client.queries) != 1 || client.queries[0].FromBlock.Uint64() != 80 {
+		t.Fatalf("Expected upgrades to be read from the creation block 80, got %+v", client.queries)
+	}
+
+	// Later calls only read the blocks after the cached upgrades
+	client.latest = 400
+	client.logs = append(client.logs, upgradeLog(tokenAddress, "Upgraded(address)", implementationAddress, 350))
+	history, err = contracts.GetProxyHistory(tokenAddress, 100, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 3 || history[2].FromBlock != 350 {
+		t.Fatalf("Expected the new upgrade in the history, got %+v", history)
+	}
+	if len(client.queries) != 2 || client.queries[1].FromBlock.Uint64() != 301 || client.queries[1].ToBlock.Uint64() != 400 {
+		t.Errorf("Expected only blocks 301-400 to be read, got %+v", client.queries[1:])
+	}
+
+	// Earlier ranges are served from the cache
+	history, err = contracts.GetProxyHistory(tokenAddress, 100, big.NewInt(200))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 2 || history[1].ToBlock != 200 || len(client.queries) != 2 {
+		t.Errorf("Expected 2 periods from the cache, got %+v after %d queries", history, len(client.queries))
+	}
+}
+
+func TestGetProxyHistoryStateError(t *testing.T) {
+	client := newFakeStateClient()
+	client.latest = 300
+	client.setSlot(tokenAddress, implementationSlot, implementationAddress)
+	contracts := historyContracts(client)
+	if _, err := contracts.GetProxyInfo(tokenAddress); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Only missing state falls back to the current implementation
+	client.storageErr = errors.New("connection refused")
+	if _, err := contracts.GetProxyHistory(tokenAddress, 100, nil); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Expected the storage error, got %v", err)
+	}
+}
+
+func TestGetProxyHistoryBeaconWithoutArchive(t *testing.T) {
+	client := newFakeStateClient()
+	client.latest = 300
+	client.pruned = true
+	client.setSlot(tokenAddress, beaconSlot, beaconAddress)
+	client.setCall(beaconAddress, "implementation()", returnAddress(upgradedImplementationAddress))
+	client.logs = []types.Log{
+		upgradeLog(tokenAddress, "BeaconUpgraded(address)", beaconAddress, 10),
+		upgradeLog(beaconAddress, "Upgraded(address)", implementationAddress, 5),
+		upgradeLog(beaconAddress, "Upgraded(address)", upgradedImplementationAddress, 150),
+	}
+	contracts := historyContracts(client)
+
+	history, err := contracts.GetProxyHistory(tokenAddress, 100, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 periods, got %+v", history)
+	}
+	if history[0].Implementation != common.HexToAddress(implementationAddress).Hex() || history[0].Beacon != common.HexToAddress(beaconAddress).Hex() {
+		t.Errorf("Expected the implementation of the earlier beacon upgrade, got %+v", history[0])
+	}
+	if history[1].Implementation != common.HexToAddress(upgradedImplementationAddress).Hex() || history[1].FromBlock != 150 {
+		t.Errorf("Unexpected second period %+v", history[1])
+	}
+}
+
+func TestGetProxyHistoryNotUpgradeable(t *testing.T) {
+	client := newFakeStateClient()
+	client.code[common.HexToAddress(tokenAddress)] = common.FromHex("0x363d3d373d3d3d363d73" + implementationAddress[2:] + "5af43d82803e903d91602b57fd5bf3")
+	contracts := historyContracts(client)
+
+	if _, err := contracts.GetProxyHistory(tokenAddress, 0, nil); err == nil {
+		t.Error("Expected error for a clone, whose implementation cannot change")
+	}
+}
+
+func TestGetEventsAcrossUpgrades(t *testing.T) {
+	alice := common.HexToAddress("0x0000000000000000000000000000000000000a11")
+	bob := common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	client := newFakeStateClient()
+	client.latest = 300
+	client.setSlot(tokenAddress, implementationSlot, implementationAddress)
+	client.logs = []types.Log{
+		transferLog(t, 120, 0, alice, bob, 5),
+		upgradeLog(tokenAddress, "Upgraded(address)", upgradedImplementationAddress, 150),
+		transferV2Log(t, 160, bob, alice, 2, "refund"),
+	}
+	contracts := historyContracts(client)
+
+	records, err := contracts.GetEvents(tokenAddress, "Transfer", EventFilter{FromBlock: 100}, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %+v", records)
+	}
+	if records[0].Signature != "Transfer(address,address,uint256)" || records[0].Args["value"].(*big.Int).Int64() != 5 {
+		t.Errorf("Unexpected record before the upgrade %+v", records[0])
+	}
+	if records[1].Signature != "Transfer(address,address,uint256,string)" || records[1].Args["memo"] != "refund" {
+		t.Errorf("Unexpected record after the upgrade %+v", records[1])
+	}
+
+	// Argument filters apply in every period
+	records, err = contracts.GetEvents(tokenAddress, "Transfer", EventFilter{
+		FromBlock:       100,
+		ArgumentFilters: map[string]interface{}{"from": bob},
+	}, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != 1 || records[0].BlockNumber != 160 {
+		t.Errorf("Expected the transfer from bob, got %+v", records)
+	}
+
+	// Without an archive node, the implementation before block 100 comes from its Upgraded event
+	client.pruned = true
+	client.logs = append([]types.Log{upgradeLog(tokenAddress, "Upgraded(address)", implementationAddress, 50)}, client.logs...)
+	records, err = contracts.GetEvents(tokenAddress, "Transfer", EventFilter{}, true)
+	if err != nil {
+		t.Fatalf("Expected no error without an archive node, got %v", err)
+	}
+	if len(records) != 2 || records[0].Signature != "Transfer(address,address,uint256)" {
+		t.Errorf("Expected records decoded per implementation, got %+v", records)
+	}
+
+	if _, err := contracts.GetEvents(tokenAddress, "Approval2", EventFilter{FromBlock: 100}, true); err == nil {
+		t.Error("Expected error for an event no implementation defines")
+	}
+}
@@ -0,0 +1,130 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// limitedChainClient rejects queries spanning more than maxRange blocks, like public providers do
+type limitedChainClient struct {
+	fakeChainClient
+	maxRange uint64
+	err      error
+
+	mu       sync.Mutex
+	calls    int
+	active   int
+	peak     int
+	rejected int
+}
+
+func (f *limitedChainClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	f.mu.Lock()
+	f.calls++
+	f.active++
+	if f.active > f.peak {
+		f.peak = f.active
+	}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.active--
+		f.mu.Unlock()
+	}()
+
+	time.Sleep(time.Millisecond)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if q.ToBlock.Uint64()-q.FromBlock.Uint64()+1 > f.maxRange {
+		f.mu.Lock()
+		f.rejected++
+		f.mu.Unlock()
+		return nil, errors.New("query returned more than 10000 results")
+	}
+	return f.fakeChainClient.FilterLogs(ctx, q)
+}
+
+func TestGetEventsChunking(t *testing.T) {
+	alice := common.HexToAddress("0x0000000000000000000000000000000000000a11")
+	bob := common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+
+	client := &limitedChainClient{maxRange: 300}
+	for block := uint64(0); block < 1000; block += 7 {
+		client.logs = append(client.logs, transferLog(t, block, 0, alice, bob, int64(block)))
+		client.logs = append(client.logs, transferLog(t, block, 1, bob, alice, int64(block)))
+	}
+
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, client, NewCache(time.Minute))
+	contracts.SetLogFetchConfig(LogFetchConfig{ChunkSize: 1000 / 3, Workers: 3})
+
+	records, err := contracts.GetEvents(tokenAddress, "Transfer", EventFilter{FromBlock: 0, ToBlock: 999}, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != len(client.logs) {
+		t.Fatalf("Expected %d records, got %d", len(client.logs), len(records))
+	}
+
+	for i := 1; i < len(records); i++ {
+		prev, cur := records[i-1], records[i]
+		if cur.BlockNumber < prev.BlockNumber || (cur.BlockNumber == prev.BlockNumber && cur.LogIndex <= prev.LogIndex) {
+			t.Fatalf("Records out of order at %d: %d/%d after %d/%d", i, cur.BlockNumber, cur.LogIndex, prev.BlockNumber, prev.LogIndex)
+		}
+	}
+
+	if client.rejected == 0 {
+		t.Error("Expected oversized chunks to be rejected and bisected")
+	}
+	if client.peak < 2 || client.peak > 3 {
+		t.Errorf("Expected between 2 and 3 concurrent queries, got %d", client.peak)
+	}
+}
+
+func TestGetEventsChunkingSingleBlockTooLarge(t *testing.T) {
+	client := &limitedChainClient{err: errors.New("Log response size exceeded")}
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, client, NewCache(time.Minute))
+
+	_, err := contracts.GetEvents(tokenAddress, "Transfer", EventFilter{FromBlock: 10, ToBlock: 13}, false)
+	if err == nil {
+		t.Fatal("Expected error when a single block exceeds the limit, got nil")
+	}
+}
+
+func TestGetEventsChunkingOtherError(t *testing.T) {
+	client := &limitedChainClient{err: errors.New("connection refused")}
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, client, NewCache(time.Minute))
+	contracts.SetLogFetchConfig(LogFetchConfig{ChunkSize: 10, Workers: 2})
+
+	_, err := contracts.GetEvents(tokenAddress, "Transfer", EventFilter{FromBlock: 0, ToBlock: 999}, false)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if client.rejected != 0 {
+		t.Errorf("Expected non-limit errors not to be bisected")
+	}
+}
+
+func TestGetEventsChunkingRateLimited(t *testing.T) {
+	for _, message := range []string{"rate limit exceeded", "request limit exceeded", "daily request count limit exceeded"} {
+		client := &limitedChainClient{err: errors.New(message)}
+		contracts := NewContracts(&fakeProvider{abi: erc20ABI}, client, NewCache(time.Minute))
+		contracts.SetLogFetchConfig(LogFetchConfig{ChunkSize: 1000, Workers: 1})
+
+		_, err := contracts.GetEvents(tokenAddress, "Transfer", EventFilter{FromBlock: 0, ToBlock: 999}, false)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected error %q, got %v", message, err)
+		}
+		if client.calls != 1 {
+			t.Errorf("Expected %q to be returned without splitting, got %d queries", message, client.calls)
+		}
+	}
+}
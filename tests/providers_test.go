@@ -0,0 +1,109 @@
+package ethereal
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	abi   string
+	err   error
+	calls int
+}
+
+func (f *fakeProvider) GetContractABI(address string) (string, error) {
+	f.calls++
+	return f.abi, f.err
+}
+
+func (f *fakeProvider) GetContractSource(address string) (string, error) {
+	f.calls++
+	return "", f.err
+}
+
+func TestChainedProviderOrder(t *testing.T) {
+	first := &fakeProvider{err: errors.New("not verified")}
+	second := &fakeProvider{abi: "[]"}
+	third := &fakeProvider{abi: `[{"type":"fallback"}]`}
+
+	p := NewChainedProvider(
+		NamedProvider{Name: "etherscan", Provider: first},
+		NamedProvider{Name: "sourcify", Provider: second},
+		NamedProvider{Name: "blockscout", Provider: third},
+	)
+
+	abi, err := p.GetContractABI("0xABC")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if abi != "[]" {
+		t.Errorf("Expected ABI from second provider, got %s", abi)
+	}
+	if third.calls != 0 {
+		t.Errorf("Expected third provider not to be queried, got %d calls", third.calls)
+	}
+
+	name, ok := p.AnsweredBy("0xabc")
+	if !ok || name != "sourcify" {
+		t.Errorf("Expected answer recorded for sourcify, got %q", name)
+	}
+}
+
+func TestChainedProviderAllFail(t *testing.T) {
+	p := NewChainedProvider(
+		NamedProvider{Name: "etherscan", Provider: &fakeProvider{err: errors.New("not verified")}},
+		NamedProvider{Name: "sourcify", Provider: &fakeProvider{err: errors.New("not found")}},
+	)
+
+	_, err := p.GetContractABI("0xabc")
+	if err == nil {
+		t.Fatal("Expected error when all providers fail, got nil")
+	}
+	if !strings.Contains(err.Error(), "etherscan: not verified") || !strings.Contains(err.Error(), "sourcify: not found") {
+		t.Errorf("Expected every provider's failure in error, got %v", err)
+	}
+	if _, ok := p.AnsweredBy("0xabc"); ok {
+		t.Error("Expected no provider recorded")
+	}
+}
+
+func TestBlockscoutGetContractABI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api" || r.URL.Query().Get("action") != "getabi" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"status":"1","message":"OK","result":"[]"}`))
+	}))
+	defer server.Close()
+
+	cache := NewCache(time.Minute)
+	cache.Set("etherscan:abi:0xabc", "from etherscan")
+	b := NewBlockscout(BlockscoutConfig{ChainID: 100, URL: server.URL}, cache)
+
+	abi, err := b.GetContractABI("0xabc")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if abi != "[]" {
+		t.Errorf("Expected ABI from Blockscout, got %s", abi)
+	}
+}
+
+func TestBlockscoutUnsupportedChain(t *testing.T) {
+	b := NewBlockscout(BlockscoutConfig{ChainID: 424242}, NewCache(time.Minute))
+	if _, err := b.GetContractABI("0xabc"); err == nil {
+		t.Error("Expected error for chain without Blockscout instance, got nil")
+	}
+}
+
+func TestGetABIWithoutProvider(t *testing.T) {
+	facade := NewEtherealFacade(nil, nil, nil, NewCache(time.Minute))
+	_, err := facade.GetABI("0xabc", false)
+	if err == nil || !strings.Contains(err.Error(), "no ABI provider configured") {
+		t.Errorf("Expected missing provider error, got %v", err)
+	}
+}
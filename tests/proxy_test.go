@@ -0,0 +1,408 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	implementationAddress = "0x00000000000000000000000000000000000000d1"
+	beaconAddress         = "0x00000000000000000000000000000000000000be"
+	adminAddress          = "0x00000000000000000000000000000000000000ad"
+)
+
+var (
+	implementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	adminSlot          = common.HexToHash("0xb53127684a568b3173ae13b9f8a6016e243e63b6e8ee1178d6a717850b5d6103")
+	beaconSlot         = common.HexToHash("0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50")
+)
+
+const transparentProxyABI = `[
+	{"type":"event","name":"Upgraded","inputs":[{"name":"implementation","type":"address","indexed":true}]},
+	{"type":"function","name":"upgradeTo","stateMutability":"nonpayable","inputs":[{"name":"newImplementation","type":"address"}],"outputs":[]},
+	{"type":"fallback","stateMutability":"payable"}
+]`
+
+const eip897ProxyABI = `[
+	{"type":"function","name":"implementation","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+	{"type":"function","name":"proxyType","stateMutability":"pure","inputs":[],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+// fakeStateClient serves storage slots and implementation() results
+type fakeStateClient struct {
+	*fakeChainClient
+	code    map[common.Address][]byte
+	storage map[common.Address]map[common.Hash]common.Hash
+	calls   map[string][]byte
+	reads   int
+	// lastBlock is the block of the last storage read
+	lastBlock *big.Int
+	// pruned fails reads at past blocks, as full nodes without archive state do
+	pruned bool
+	// deployed is the creation block of accounts with code
+	deployed map[common.Address]uint64
+	// storageErr fails every storage read
+	storageErr error
+}
+
+func (f *fakeStateClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	if f.pruned && blockNumber != nil {
+		return nil, errMissingState
+	}
+	if block, ok := f.deployed[account]; ok && blockNumber != nil && blockNumber.Uint64() < block {
+		return nil, nil
+	}
+	return f.code[account], nil
+}
+
+func (f *fakeStateClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	f.reads++
+	f.lastBlock = blockNumber
+	if f.storageErr != nil {
+		return nil, f.storageErr
+	}
+	if f.pruned && blockNumber != nil {
+		return nil, errMissingState
+	}
+	value := f.storage[account][key]
+	return value.Bytes(), nil
+}
+
+func (f *fakeStateClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if f.pruned && blockNumber != nil {
+		return nil, errMissingState
+	}
+	if result, ok := f.calls[callKey(*call.To, call.Data)]; ok {
+		return result, nil
+	}
+	return nil, errors.New("execution reverted")
+}
+
+var errMissingState = errors.New("missing trie node")
+
+func callKey(to common.Address, data []byte) string {
+	return to.Hex() + common.Bytes2Hex(data)
+}
+
+// setCall sets the result of calling a function without arguments
+func (f *fakeStateClient) setCall(address string, signature string, result []byte) {
+	f.calls[callKey(common.HexToAddress(address), crypto.Keccak256([]byte(signature))[:4])] = result
+}
+
+func (f *fakeStateClient) setSlot(address string, slot common.Hash, value string) {
+	account := common.HexToAddress(address)
+	if f.storage[account] == nil {
+		f.storage[account] = make(map[common.Hash]common.Hash)
+	}
+	f.storage[account][slot] = common.BytesToHash(common.HexToAddress(value).Bytes())
+}
+
+func newFakeStateClient() *fakeStateClient {
+	return &fakeStateClient{
+		fakeChainClient: &fakeChainClient{},
+		code:            make(map[common.Address][]byte),
+		storage:         make(map[common.Address]map[common.Hash]common.Hash),
+		calls:           make(map[string][]byte),
+		deployed:        make(map[common.Address]uint64),
+	}
+}
+
+// returnAddress encodes an address as returned by implementation() or masterCopy()
+func returnAddress(address string) []byte {
+	return common.BytesToHash(common.HexToAddress(address).Bytes()).Bytes()
+}
+
+func proxyContracts(client ChainClient, abis mapProvider) *Contracts {
+	return NewContracts(abis, client, NewCache(time.Minute))
+}
+
+func TestGetProxyInfoEIP1967(t *testing.T) {
+	client := newFakeStateClient()
+	client.setSlot(tokenAddress, implementationSlot, implementationAddress)
+	client.setSlot(tokenAddress, adminSlot, adminAddress)
+	contracts := proxyContracts(client, mapProvider{})
+
+	info, err := contracts.GetProxyInfo(tokenAddress)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !info.IsProxy || info.ProxyType != "EIP1967" {
+		t.Errorf("Expected EIP1967 proxy, got %+v", info)
+	}
+	if info.Implementation != common.HexToAddress(implementationAddress).Hex() {
+		t.Errorf("Unexpected implementation %s", info.Implementation)
+	}
+	if info.Admin != common.HexToAddress(adminAddress).Hex() {
+		t.Errorf("Unexpected admin %s", info.Admin)
+	}
+
+	// Detection is cached
+	reads := client.reads
+	if _, err := contracts.GetProxyInfo(tokenAddress); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if client.reads != reads {
+		t.Errorf("Expected cached proxy info, got %d more storage reads", client.reads-reads)
+	}
+
+	implementation, err := contracts.GetImplementationAddress(tokenAddress)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if implementation != info.Implementation {
+		t.Errorf("Expected %s, got %s", info.Implementation, implementation)
+	}
+}
+
+func TestGetProxyInfoBeacon(t *testing.T) {
+	client := newFakeStateClient()
+	client.setSlot(tokenAddress, beaconSlot, beaconAddress)
+	client.setCall(beaconAddress, "implementation()", returnAddress(implementationAddress))
+	contracts := proxyContracts(client, mapProvider{})
+
+	info, err := contracts.GetProxyInfo(tokenAddress)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if info.ProxyType != "EIP1967Beacon" || info.Beacon != common.HexToAddress(beaconAddress).Hex() {
+		t.Errorf("Expected beacon proxy, got %+v", info)
+	}
+	if info.Implementation != common.HexToAddress(implementationAddress).Hex() {
+		t.Errorf("Unexpected implementation %s", info.Implementation)
+	}
+}
+
+func TestGetProxyInfoPatterns(t *testing.T) {
+	clone := common.FromHex("0x363d3d373d3d3d363d73" + implementationAddress[2:] + "5af43d82803e903d91602b57fd5bf3")
+
+	tests := []struct {
+		name      string
+		setup     func(client *fakeStateClient)
+		proxyType string
+	}{
+		{"EIP-1167 clone", func(client *fakeStateClient) {
+			client.code[common.HexToAddress(tokenAddress)] = clone
+		}, "EIP1167"},
+		{"EIP-1822 UUPS", func(client *fakeStateClient) {
+			client.setSlot(tokenAddress, crypto.Keccak256Hash([]byte("PROXIABLE")), implementationAddress)
+		}, "EIP1822"},
+		{"ZeppelinOS", func(client *fakeStateClient) {
+			client.setSlot(tokenAddress, crypto.Keccak256Hash([]byte("org.zeppelinos.proxy.implementation")), implementationAddress)
+		}, "ZeppelinOS"},
+		{"Gnosis Safe", func(client *fakeStateClient) {
+			client.setSlot(tokenAddress, common.Hash{}, implementationAddress)
+			client.setCall(tokenAddress, "masterCopy()", returnAddress(implementationAddress))
+		}, "GnosisSafe"},
+		{"slot 0 without masterCopy", func(client *fakeStateClient) {
+			client.setSlot(tokenAddress, common.Hash{}, implementationAddress)
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeStateClient()
+			tt.setup(client)
+			info, err := proxyContracts(client, mapProvider{}).GetProxyInfo(tokenAddress)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if info.ProxyType != tt.proxyType || info.IsProxy != (tt.proxyType != "") {
+				t.Fatalf("Expected %q proxy, got %+v", tt.proxyType, info)
+			}
+			if info.IsProxy && info.Implementation != common.HexToAddress(implementationAddress).Hex() {
+				t.Errorf("Unexpected implementation %s", info.Implementation)
+			}
+		})
+	}
+}
+
+func TestGetProxyInfoChain(t *testing.T) {
+	const finalAddress = "0x00000000000000000000000000000000000000f1"
+	client := newFakeStateClient()
+	client.setSlot(tokenAddress, implementationSlot, implementationAddress)
+	client.code[common.HexToAddress(implementationAddress)] = common.FromHex("0x363d3d373d3d3d363d73" + finalAddress[2:] + "5af43d82803e903d91602b57fd5bf3")
+	contracts := proxyContracts(client, mapProvider{
+		strings.ToLower(tokenAddress): transparentProxyABI,
+		finalAddress:                  erc20ABI,
+	})
+
+	info, err := contracts.GetProxyInfo(tokenAddress)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if info.ProxyType != "EIP1967" || info.Implementation != common.HexToAddress(finalAddress).Hex() {
+		t.Errorf("Expected the final implementation, got %+v", info)
+	}
+	if len(info.Chain) != 2 || info.Chain[0] != common.HexToAddress(implementationAddress).Hex() {
+		t.Errorf("Unexpected chain %v", info.Chain)
+	}
+
+	contractABI, err := contracts.GetABI(tokenAddress, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := contractABI.Methods["transfer"]; !ok {
+		t.Error("Expected the final implementation's functions")
+	}
+}
+
+func TestGetProxyInfoCycle(t *testing.T) {
+	client := newFakeStateClient()
+	client.setSlot(tokenAddress, implementationSlot, implementationAddress)
+	client.setSlot(implementationAddress, implementationSlot, tokenAddress)
+	contracts := proxyContracts(client, mapProvider{})
+
+	_, err := contracts.GetProxyInfo(tokenAddress)
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+}
+
+func TestGetProxyInfoEIP897(t *testing.T) {
+	client := newFakeStateClient()
+	client.setCall(tokenAddress, "implementation()", returnAddress(implementationAddress))
+	contracts := proxyContracts(client, mapProvider{strings.ToLower(tokenAddress): eip897ProxyABI})
+
+	info, err := contracts.GetProxyInfo(tokenAddress)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if info.ProxyType != "EIP897" || info.Implementation != common.HexToAddress(implementationAddress).Hex() {
+		t.Errorf("Expected EIP897 proxy, got %+v", info)
+	}
+}
+
+func TestGetProxyInfoNotProxy(t *testing.T) {
+	client := newFakeStateClient()
+	client.setCall(tokenAddress, "implementation()", returnAddress(implementationAddress))
+	// implementation() without proxyType() is not taken as EIP-897
+	contracts := proxyContracts(client, mapProvider{strings.ToLower(tokenAddress): erc20ABI})
+
+	info, err := contracts.GetProxyInfo(tokenAddress)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if info.IsProxy {
+		t.Errorf("Expected no proxy, got %+v", info)
+	}
+	if _, err := contracts.GetImplementationAddress(tokenAddress); err == nil {
+		t.Error("Expected error for a contract that is not a proxy")
+	}
+}
+
+func TestGetProxyInfoWithoutClient(t *testing.T) {
+	contracts := proxyContracts(nil, mapProvider{})
+	if _, err := contracts.GetProxyInfo(tokenAddress); err == nil {
+		t.Error("Expected error without an RPC client")
+	}
+}
+
+func TestGetABIResolvesProxy(t *testing.T) {
+	client := newFakeStateClient()
+	client.setSlot(tokenAddress, implementationSlot, implementationAddress)
+	contracts := proxyContracts(client, mapProvider{
+		strings.ToLower(tokenAddress): transparentProxyABI,
+		implementationAddress:         erc20ABI,
+	})
+
+	contractABI, err := contracts.GetABI(tokenAddress, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, name := range []string{"transfer", "upgradeTo"} {
+		if _, ok := contractABI.Methods[name]; !ok {
+			t.Errorf("Expected merged ABI to have %s", name)
+		}
+	}
+	if _, ok := contractABI.ABI.Events["Upgraded"]; !ok {
+		t.Error("Expected merged ABI to have Upgraded")
+	}
+
+	proxyABI, err := contracts.GetABI(tokenAddress, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := proxyABI.Methods["transfer"]; ok {
+		t.Error("Expected the proxy's own ABI without resolveProxy")
+	}
+
+	events, err := contracts.ListEvents(tokenAddress, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 3 {
+		t.Errorf("Expected 3 events, got %d", len(events))
+	}
+}
+
+func TestGetABIUnverifiedProxy(t *testing.T) {
+	client := newFakeStateClient()
+	client.setSlot(tokenAddress, implementationSlot, implementationAddress)
+	contracts := proxyContracts(client, mapProvider{implementationAddress: erc20ABI})
+
+	contractABI, err := contracts.GetABI(tokenAddress, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := contractABI.Methods["transfer"]; !ok {
+		t.Error("Expected the implementation ABI")
+	}
+
+	if _, err := contracts.GetABI(tokenAddress, false); err == nil {
+		t.Error("Expected error for the unverified proxy itself")
+	}
+}
+
+func TestGetEventsResolvesProxy(t *testing.T) {
+	alice := common.HexToAddress("0x0000000000000000000000000000000000000a11")
+	bob := common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	client := newFakeStateClient()
+	client.logs = []types.Log{transferLog(t, 100, 0, alice, bob, 5)}
+	client.setSlot(tokenAddress, implementationSlot, implementationAddress)
+	contracts := proxyContracts(client, mapProvider{
+		strings.ToLower(tokenAddress): transparentProxyABI,
+		implementationAddress:         erc20ABI,
+	})
+
+	if _, err := contracts.GetEvents(tokenAddress, "Transfer", EventFilter{FromBlock: 100, ToBlock: 200}, false); err == nil {
+		t.Error("Expected error for an event of the implementation without resolveProxy")
+	}
+
+	records, err := contracts.GetEvents(tokenAddress, "Transfer", EventFilter{FromBlock: 100, ToBlock: 200}, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != 1 || records[0].Args["to"] != bob {
+		t.Errorf("Unexpected records %+v", records)
+	}
+}
+
+func TestMergeABIs(t *testing.T) {
+	first, err := ParseABI(`[{"type":"function","name":"transfer","stateMutability":"nonpayable","inputs":[{"name":"recipient","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]}]`)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	second, err := ParseABI(erc20ABI)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+
+	merged, err := MergeABIs(first, second)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(merged.Methods) != len(second.Methods) || len(merged.ABI.Events) != len(second.ABI.Events) {
+		t.Errorf("Expected %d functions and %d events, got %d and %d", len(second.Methods), len(second.ABI.Events), len(merged.Methods), len(merged.ABI.Events))
+	}
+	if merged.Methods["transfer"].Inputs[0].Name != "recipient" {
+		t.Errorf("Expected the first definition to win, got %+v", merged.Methods["transfer"].Inputs)
+	}
+}
@@ -0,0 +1,205 @@
+package ethereal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	registryAddress = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+	registryABI     = `[{"type":"event","name":"Transfer","inputs":[]}]`
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestABIRegistryDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, registryAddress+".json"), registryABI)
+	writeFile(t, filepath.Join(dir, "1", "0x0000000000000000000000000000000000000001.json"), `{"abi":[]}`)
+	writeFile(t, filepath.Join(dir, "5", "0x0000000000000000000000000000000000000002.json"), `[]`)
+
+	r, err := NewABIRegistry(ABIRegistryConfig{ChainID: 1, Dirs: []string{dir}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	abi, err := r.GetContractABI("0x742d35cc6634c0532925a3b844bc454e4438f44e")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if abi != registryABI {
+		t.Errorf("Unexpected ABI %s", abi)
+	}
+
+	if _, err := r.GetContractABI("0x0000000000000000000000000000000000000001"); err != nil {
+		t.Errorf("Expected ABI from chain subdirectory, got %v", err)
+	}
+	if _, err := r.GetContractABI("0x0000000000000000000000000000000000000002"); err == nil {
+		t.Error("Expected ABI of another chain to be ignored")
+	}
+}
+
+func TestABIRegistryArtifactsAndMapping(t *testing.T) {
+	dir := t.TempDir()
+	foundry := filepath.Join(dir, "out")
+	hardhat := filepath.Join(dir, "artifacts")
+
+	writeFile(t, filepath.Join(foundry, "Token.sol", "Token.json"), `{"abi":`+registryABI+`,"bytecode":{"object":"0x6080"}}`)
+	writeFile(t, filepath.Join(foundry, "build-info", "abc.json"), `{"input":{}}`)
+	writeFile(t, filepath.Join(hardhat, "contracts", "Vault.sol", "Vault.json"), `{"_format":"hh-sol-artifact-1","contractName":"Vault","sourceName":"contracts/Vault.sol","abi":[],"bytecode":"0x6081"}`)
+	writeFile(t, filepath.Join(hardhat, "contracts", "Vault.sol", "Vault.dbg.json"), `{"buildInfo":"../../build-info/abc.json"}`)
+	writeFile(t, filepath.Join(dir, "abis", "Pool.json"), `[{"type":"fallback"}]`)
+
+	mapping := filepath.Join(dir, "mainnet.json")
+	writeFile(t, mapping, `{
+		"`+registryAddress+`": "Token",
+		"0x0000000000000000000000000000000000000001": "contracts/Vault.sol:Vault",
+		"0x0000000000000000000000000000000000000002": "abis/Pool.json",
+		"0x0000000000000000000000000000000000000003": [{"type":"receive","stateMutability":"payable"}]
+	}`)
+
+	r, err := NewABIRegistry(ABIRegistryConfig{
+		ChainID:      1,
+		ArtifactDirs: []string{foundry, hardhat},
+		MappingFiles: map[int]string{1: mapping},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := map[string]string{
+		registryAddress: registryABI,
+		"0x0000000000000000000000000000000000000001": `[]`,
+		"0x0000000000000000000000000000000000000002": `[{"type":"fallback"}]`,
+		"0x0000000000000000000000000000000000000003": `[{"type":"receive","stateMutability":"payable"}]`,
+	}
+	for address, want := range expected {
+		got, err := r.GetContractABI(address)
+		if err != nil {
+			t.Errorf("Expected ABI for %s, got %v", address, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Expected ABI %s for %s, got %s", want, address, got)
+		}
+	}
+
+	artifact, err := r.GetArtifact("Token")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if artifact.Bytecode != "0x6080" {
+		t.Errorf("Expected Foundry bytecode object, got %s", artifact.Bytecode)
+	}
+}
+
+func TestABIRegistryHotReload(t *testing.T) {
+	dir := t.TempDir()
+
+	r, err := NewABIRegistry(ABIRegistryConfig{ChainID: 1, Dirs: []string{dir}, ReloadInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer r.Close()
+
+	if _, err := r.GetContractABI(registryAddress); err == nil {
+		t.Fatal("Expected no ABI before the file exists")
+	}
+
+	writeFile(t, filepath.Join(dir, registryAddress+".json"), registryABI)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := r.GetContractABI(registryAddress); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected ABI to be picked up by hot reload")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A broken file keeps the previous contents and reports the error
+	writeFile(t, filepath.Join(dir, "0x0000000000000000000000000000000000000001.json"), `{"not":"an abi"}`)
+	deadline = time.Now().Add(time.Second)
+	for r.LastError() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected reload error to be reported")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := r.GetContractABI(registryAddress); err != nil {
+		t.Errorf("Expected previous ABIs to be kept after failed reload, got %v", err)
+	}
+}
+
+func TestABIRegistryAmbiguousArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	writeFile(t, filepath.Join(out, "Token.sol", "Token.json"), `{"abi":`+registryABI+`,"bytecode":{"object":"0x6080"}}`)
+	writeFile(t, filepath.Join(out, "MockToken.sol", "Token.json"), `{"abi":[],"bytecode":{"object":"0x6081"}}`)
+
+	r, err := NewABIRegistry(ABIRegistryConfig{ChainID: 1, ArtifactDirs: []string{out}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := r.GetArtifact("Token"); err == nil || !strings.Contains(err.Error(), "MockToken.sol:Token, Token.sol:Token") {
+		t.Errorf("Expected error listing both qualified names, got %v", err)
+	}
+	artifact, err := r.GetArtifact("MockToken.sol:Token")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if artifact.Bytecode != "0x6081" {
+		t.Errorf("Expected the mock artifact, got %s", artifact.Bytecode)
+	}
+
+	mapping := filepath.Join(dir, "mainnet.json")
+	writeFile(t, mapping, `{"`+registryAddress+`": "Token"}`)
+	if _, err := NewABIRegistry(ABIRegistryConfig{ChainID: 1, ArtifactDirs: []string{out}, MappingFiles: map[int]string{1: mapping}}); err == nil {
+		t.Error("Expected error for a mapping to an ambiguous name")
+	}
+}
+
+func TestABIRegistryHotReloadMappedFile(t *testing.T) {
+	dir := t.TempDir()
+	pool := filepath.Join(dir, "abis", "Pool.json")
+	writeFile(t, pool, `[{"type":"fallback"}]`)
+	mapping := filepath.Join(dir, "mappings", "mainnet.json")
+	writeFile(t, mapping, `{"`+registryAddress+`": "../abis/Pool.json"}`)
+
+	r, err := NewABIRegistry(ABIRegistryConfig{ChainID: 1, MappingFiles: map[int]string{1: mapping}, ReloadInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer r.Close()
+
+	// The file is outside any watched directory
+	writeFile(t, pool, registryABI)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(pool, later, later); err != nil {
+		t.Fatalf("Failed to change modification time: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if abi, _ := r.GetContractABI(registryAddress); abi == registryABI {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the mapped ABI file to be reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,134 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type fakeHeaderClient struct {
+	times []uint64
+	calls int
+}
+
+func (f *fakeHeaderClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	f.calls++
+	if number == nil {
+		number = big.NewInt(int64(len(f.times) - 1))
+	}
+	if number.Int64() >= int64(len(f.times)) {
+		return nil, errors.New("not found")
+	}
+	return &types.Header{Number: number, Time: f.times[number.Int64()]}, nil
+}
+
+func TestRPCBlockResolver(t *testing.T) {
+	// Blocks 0-9 every 12 seconds from 1000, then 10-12 share a timestamp
+	client := &fakeHeaderClient{times: []uint64{1000, 1012, 1024, 1036, 1048, 1060, 1072, 1084, 1096, 1108, 1120, 1120, 1120, 1132}}
+	resolver := NewRPCBlockResolver(client, NewCache(time.Minute))
+
+	tests := []struct {
+		timestamp int64
+		closest   string
+		block     int64
+	}{
+		{1036, "before", 3},
+		{1036, "after", 3},
+		{1040, "before", 3},
+		{1040, "after", 4},
+		{1040, "", 4},
+		{1120, "before", 12},
+		{1120, "after", 10},
+		{900, "after", 0},
+		{2000, "before", 13},
+		{1132, "after", 13},
+	}
+	for _, tt := range tests {
+		block, err := resolver.GetBlockByTimestamp(tt.timestamp, tt.closest)
+		if err != nil {
+			t.Errorf("%d %s: expected no error, got %v", tt.timestamp, tt.closest, err)
+			continue
+		}
+		if block != tt.block {
+			t.Errorf("%d %s: expected block %d, got %d", tt.timestamp, tt.closest, tt.block, block)
+		}
+	}
+
+	if _, err := resolver.GetBlockByTimestamp(900, "before"); err == nil {
+		t.Error("Expected error for a time before genesis, got nil")
+	}
+	if _, err := resolver.GetBlockByTimestamp(2000, "after"); err == nil {
+		t.Error("Expected error for a time after the latest block, got nil")
+	}
+	if _, err := resolver.GetBlockByTimestamp(1040, "closest"); err == nil {
+		t.Error("Expected error for an invalid closest value, got nil")
+	}
+}
+
+func TestRPCBlockResolverMatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	times := make([]uint64, 100000)
+	current := uint64(1600000000)
+	for i := range times {
+		times[i] = current
+		// Mostly 12 second slots with occasional missed slots and a slower era
+		gap := uint64(12)
+		if rng.Intn(20) == 0 {
+			gap += 12 * uint64(rng.Intn(3))
+		}
+		if i > 60000 {
+			gap = 2
+		}
+		current += gap
+	}
+	client := &fakeHeaderClient{times: times}
+	resolver := NewRPCBlockResolver(client, NewCache(time.Minute))
+
+	for i := 0; i < 50; i++ {
+		timestamp := int64(times[0]) + rng.Int63n(int64(times[len(times)-1]-times[0]))
+
+		expected := int64(0)
+		for b, blockTime := range times {
+			if int64(blockTime) <= timestamp {
+				expected = int64(b)
+			}
+		}
+
+		client.calls = 0
+		block, err := resolver.GetBlockByTimestamp(timestamp, "before")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if block != expected {
+			t.Fatalf("Timestamp %d: expected block %d, got %d", timestamp, expected, block)
+		}
+		// A plain bisection takes 17 lookups over 100000 blocks
+		if client.calls > 40 {
+			t.Errorf("Timestamp %d: expected a bounded number of lookups, got %d", timestamp, client.calls)
+		}
+	}
+}
+
+func TestRPCBlockResolverCachesHeaders(t *testing.T) {
+	client := &fakeHeaderClient{times: []uint64{1000, 1012, 1024, 1036, 1048, 1060, 1072, 1084, 1096, 1108}}
+	resolver := NewRPCBlockResolver(client, NewCache(time.Minute))
+
+	if _, err := resolver.GetBlockByTimestamp(1050, "before"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	first := client.calls
+
+	client.calls = 0
+	if _, err := resolver.GetBlockByTimestamp(1050, "before"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Only the latest header is fetched again
+	if client.calls != 1 {
+		t.Errorf("Expected 1 lookup after caching (first call made %d), got %d", first, client.calls)
+	}
+}
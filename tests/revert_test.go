@@ -0,0 +1,133 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// fakeDataError mimics the JSON-RPC errors ethclient returns for reverted calls
+type fakeDataError struct {
+	data interface{}
+}
+
+func (e *fakeDataError) Error() string          { return "execution reverted" }
+func (e *fakeDataError) ErrorData() interface{} { return e.data }
+
+func packRevert(t *testing.T, signature string, args ...interface{}) []byte {
+	t.Helper()
+	name := signature[:strings.Index(signature, "(")]
+	var inputs []string
+	for i, typ := range strings.Split(strings.Trim(signature[len(name):], "()"), ",") {
+		inputs = append(inputs, fmt.Sprintf(`{"name":"arg%d","type":"%s"}`, i, typ))
+	}
+	contractABI, err := ParseABI(fmt.Sprintf(`[{"type":"error","name":"%s","inputs":[%s]}]`, name, strings.Join(inputs, ",")))
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+	abiErr := contractABI.ABI.Errors[name]
+	packed, err := abiErr.Inputs.Pack(args...)
+	if err != nil {
+		t.Fatalf("Failed to pack %s: %v", signature, err)
+	}
+	return append(abiErr.ID[:4:4], packed...)
+}
+
+func TestDecodeRevert(t *testing.T) {
+	contractABI, err := ParseABI(erc20ABI)
+	if err != nil {
+		t.Fatalf("Failed to parse ABI: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		kind   string
+		reason string
+	}{
+		{"error string", packRevert(t, "Error(string)", "insufficient allowance"), "Error", "insufficient allowance"},
+		{"panic", packRevert(t, "Panic(uint256)", big.NewInt(0x11)), "Panic", "panic: arithmetic overflow or underflow (0x11)"},
+		{"unknown panic", packRevert(t, "Panic(uint256)", big.NewInt(0x99)), "Panic", "panic: unknown code 0x99"},
+		{"custom error", packRevert(t, "InsufficientBalance(uint256,uint256)", big.NewInt(1), big.NewInt(5)), "InsufficientBalance", "InsufficientBalance(available: 1, required: 5)"},
+		{"unknown error", packRevert(t, "Unauthorized(address)", common.Address{}), "", "unknown error"},
+		{"empty", nil, "", "no reason given"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revert := DecodeRevert(tt.data, contractABI)
+			if revert.Name != tt.kind {
+				t.Errorf("Expected %q, got %q", tt.kind, revert.Name)
+			}
+			if !strings.HasPrefix(revert.Reason, tt.reason) {
+				t.Errorf("Expected reason %q, got %q", tt.reason, revert.Reason)
+			}
+		})
+	}
+}
+
+func TestContractsDecodeRevert(t *testing.T) {
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, nil, NewCache(time.Minute))
+	data := packRevert(t, "InsufficientBalance(uint256,uint256)", big.NewInt(1), big.NewInt(5))
+	rpcErr := &fakeDataError{data: hexutil.Encode(data)}
+
+	err := fmt.Errorf("call failed: %w", contracts.DecodeRevert(rpcErr, tokenAddress))
+
+	var revert *RevertError
+	if !errors.As(err, &revert) {
+		t.Fatalf("Expected a RevertError, got %v", err)
+	}
+	if revert.Name != "InsufficientBalance" || revert.Args["required"].(*big.Int).Int64() != 5 {
+		t.Errorf("Unexpected revert %+v", revert)
+	}
+	if !errors.Is(err, rpcErr) {
+		t.Error("Expected the RPC error to stay reachable")
+	}
+
+	plain := errors.New("connection refused")
+	if contracts.DecodeRevert(plain, tokenAddress) != plain {
+		t.Error("Expected errors without revert data to be returned unchanged")
+	}
+}
+
+type revertingTransactionClient struct {
+	err error
+}
+
+func (c revertingTransactionClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (c revertingTransactionClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (c revertingTransactionClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return 0, c.err
+}
+
+func TestSignTransactionRevert(t *testing.T) {
+	history := &ethereum.FeeHistory{
+		Reward:       [][]*big.Int{{big.NewInt(1e9), big.NewInt(2e9), big.NewInt(3e9)}},
+		BaseFee:      []*big.Int{big.NewInt(10e9), big.NewInt(10e9)},
+		GasUsedRatio: []float64{0.5},
+	}
+	client := revertingTransactionClient{err: &fakeDataError{data: hexutil.Encode(packRevert(t, "Error(string)", "paused"))}}
+	a := NewAccounts()
+	a.SetTransactionBuilder(client, NewFeeOracle(nil, &fakeFeeHistory{history: history}))
+
+	account := &Account{PrivateKey: "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"}
+	_, err := a.SignTransaction(account, tokenAddress, "0", nil)
+
+	var revert *RevertError
+	if !errors.As(err, &revert) || revert.Reason != "paused" {
+		t.Errorf("Expected revert with reason paused, got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package ethereal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// fakeRPCError is a JSON-RPC error response
+type fakeRPCError struct {
+	code    int
+	message string
+}
+
+func (e *fakeRPCError) Error() string  { return e.message }
+func (e *fakeRPCError) ErrorCode() int { return e.code }
+
+// simulateClient answers raw JSON-RPC requests with canned JSON responses
+type simulateClient struct {
+	*fakeStateClient
+	responses map[string]string
+	errs      map[string]error
+	methods   []string
+	params    map[string]string // JSON encoded params by method
+}
+
+func newSimulateClient() *simulateClient {
+	return &simulateClient{
+		fakeStateClient: newFakeStateClient(),
+		responses:       make(map[string]string),
+		errs:            make(map[string]error),
+		params:          make(map[string]string),
+	}
+}
+
+func (s *simulateClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	s.methods = append(s.methods, method)
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	s.params[method] = string(encoded)
+	if err := s.errs[method]; err != nil {
+		return err
+	}
+	response, ok := s.responses[method]
+	if !ok {
+		return &fakeRPCError{code: -32601, message: "the method " + method + " does not exist/is not available"}
+	}
+	return json.Unmarshal([]byte(response), result)
+}
+
+func simulateContracts(client *simulateClient) *Contracts {
+	return proxyContracts(client, mapProvider{strings.ToLower(tokenAddress): erc20ABI})
+}
+
+const (
+	simulationSender = "0x0000000000000000000000000000000000000a11"
+	trueWord         = "0x0000000000000000000000000000000000000000000000000000000000000001"
+)
+
+func transferCall() SimulationCall {
+	return SimulationCall{
+		From:   simulationSender,
+		To:     tokenAddress,
+		Method: "transfer",
+		Args:   []interface{}{"0x0000000000000000000000000000000000000b0b", big.NewInt(100)},
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	client := newSimulateClient()
+	client.responses["eth_simulateV1"] = `[{"number":"0x4d3","calls":[{"returnData":"` + trueWord + `","gasUsed":"0xb411","status":"0x1",
+		"logs":[{"address":"` + tokenAddress + `","topics":["0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"],
+			"data":"0x","blockNumber":"0x4d3","transactionHash":"0x` + strings.Repeat("00", 32) + `","transactionIndex":"0x0",
+			"blockHash":"0x` + strings.Repeat("00", 32) + `","logIndex":"0x0","removed":false}]}]}]`
+	contracts := simulateContracts(client)
+
+	nonce := uint64(7)
+	slot := common.HexToHash("0x01")
+	result, err := contracts.Simulate(transferCall(), SimulateOpts{
+		Block: 1234,
+		StateOverrides: StateOverride{simulationSender: {
+			Balance:   big.NewInt(1e18),
+			Nonce:     &nonce,
+			StateDiff: map[common.Hash]common.Hash{slot: common.HexToHash("0x64")},
+		}},
+		BlockOverrides: &BlockOverrides{BaseFee: big.NewInt(0)},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Err != nil {
+		t.Fatalf("Expected successful call, got %v", result.Err)
+	}
+	if result.Result.Values()["arg0"] != true {
+		t.Errorf("Expected true, got %+v", result.Result.Outputs)
+	}
+	if result.GasUsed != 0xb411 {
+		t.Errorf("Expected gas used 46097, got %d", result.GasUsed)
+	}
+	if len(result.Logs) != 1 || result.Logs[0].Address != common.HexToAddress(tokenAddress) {
+		t.Errorf("Unexpected logs %+v", result.Logs)
+	}
+
+	params := client.params["eth_simulateV1"]
+	for _, want := range []string{
+		`"data":"0xa9059cbb`,
+		`"balance":"0xde0b6b3a7640000"`,
+		`"nonce":"0x7"`,
+		`"stateDiff":{"0x0000000000000000000000000000000000000000000000000000000000000001":"0x0000000000000000000000000000000000000000000000000000000000000064"}`,
+		`"blockOverrides":{"baseFeePerGas":"0x0"}`,
+		`"0x4d2"]`,
+	} {
+		if !strings.Contains(params, want) {
+			t.Errorf("Expected params to contain %s, got %s", want, params)
+		}
+	}
+}
+
+func TestSimulateFailures(t *testing.T) {
+	revert := hexutil.Encode(packRevert(t, "InsufficientBalance(uint256,uint256)", big.NewInt(1), big.NewInt(100)))
+	client := newSimulateClient()
+	client.responses["eth_simulateV1"] = `[{"calls":[
+		{"returnData":"` + revert + `","gasUsed":"0x5a3c","status":"0x0","logs":[],"error":{"code":3,"message":"execution reverted"}},
+		{"returnData":"0x","gasUsed":"0x5208","status":"0x0","logs":[],"error":{"code":-32015,"message":"out of gas"}}]}]`
+	contracts := simulateContracts(client)
+
+	results, err := contracts.SimulateBundle([]SimulationCall{transferCall(), transferCall()}, SimulateOpts{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var revertErr *RevertError
+	if !errors.As(results[0].Err, &revertErr) || revertErr.Name != "InsufficientBalance" {
+		t.Errorf("Expected InsufficientBalance revert, got %v", results[0].Err)
+	}
+	if results[0].GasUsed != 0x5a3c {
+		t.Errorf("Expected gas used of the reverted call, got %d", results[0].GasUsed)
+	}
+	if results[1].Err == nil || errors.As(results[1].Err, &revertErr) {
+		t.Errorf("Expected out of gas error, got %v", results[1].Err)
+	}
+
+	if _, err := contracts.Simulate(SimulationCall{To: tokenAddress, Method: "missing"}, SimulateOpts{}); err == nil {
+		t.Error("Expected error for an unknown function")
+	}
+	if _, err := contracts.Simulate(transferCall(), SimulateOpts{StateOverrides: StateOverride{"0x1234": {}}}); err == nil {
+		t.Error("Expected error for an invalid override address")
+	}
+}
+
+func TestSimulateWithoutSimulateV1(t *testing.T) {
+	client := newSimulateClient()
+	client.responses["eth_call"] = `"` + trueWord + `"`
+	client.responses["eth_estimateGas"] = `"0xb411"`
+	contracts := simulateContracts(client)
+
+	overrides := SimulateOpts{StateOverrides: StateOverride{tokenAddress: {Code: []byte{0x60, 0x80}}}}
+	result, err := contracts.Simulate(transferCall(), overrides)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Err != nil || result.Result.Values()["arg0"] != true {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if result.GasUsed != 0xb411 {
+		t.Errorf("Expected estimated gas 46097, got %d", result.GasUsed)
+	}
+	code := `{"` + strings.ToLower(tokenAddress) + `":{"code":"0x6080"}}`
+	if !strings.Contains(client.params["eth_call"], `"latest",`+code) {
+		t.Errorf("Expected eth_call with state overrides, got %s", client.params["eth_call"])
+	}
+	if !strings.Contains(client.params["eth_estimateGas"], code) {
+		t.Errorf("Expected eth_estimateGas with state overrides, got %s", client.params["eth_estimateGas"])
+	}
+
+	// Block overrides use geth's eth_call names, and gas is not estimated without them
+	client.methods = nil
+	overrides.BlockOverrides = &BlockOverrides{BaseFee: big.NewInt(1), FeeRecipient: simulationSender}
+	result, err = contracts.Simulate(transferCall(), overrides)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(client.params["eth_call"], `{"baseFee":"0x1","coinbase":"`+simulationSender+`"}`) {
+		t.Errorf("Expected eth_call with block overrides, got %s", client.params["eth_call"])
+	}
+	if result.GasUsed != 0 || strings.Join(client.methods, ",") != "eth_simulateV1,eth_call" {
+		t.Errorf("Expected no gas estimate, got %d after %v", result.GasUsed, client.methods)
+	}
+
+	// Reverts are decoded from eth_call errors
+	client.errs["eth_call"] = &fakeDataError{data: hexutil.Encode(packRevert(t, "Error(string)", "paused"))}
+	result, err = contracts.Simulate(transferCall(), SimulateOpts{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var revertErr *RevertError
+	if !errors.As(result.Err, &revertErr) || revertErr.Reason != "paused" {
+		t.Errorf("Expected revert with reason paused, got %v", result.Err)
+	}
+
+	if _, err := contracts.SimulateBundle([]SimulationCall{transferCall(), transferCall()}, SimulateOpts{}); err == nil || !strings.Contains(err.Error(), "eth_simulateV1") {
+		t.Errorf("Expected error for a bundle without eth_simulateV1, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package ethereal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sourcifyMetadata = `{"compiler":{"version":"0.8.24"},"output":{"abi":[{"type":"event","name":"Transfer","inputs":[]}]}}`
+
+func newFakeSourcify(t *testing.T, status string, config SourcifyConfig) *Sourcify {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/files/any/1/") {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"Files have not been found!"}`))
+			return
+		}
+		matchDir := "/data/repository/contracts/" + status + "_match/1/0xabc"
+		body, _ := json.Marshal(map[string]interface{}{
+			"status": status,
+			"files": []map[string]string{
+				{"name": "metadata.json", "path": matchDir + "/metadata.json", "content": sourcifyMetadata},
+				{"name": "Token.sol", "path": matchDir + "/sources/contracts/Token.sol", "content": "contract Token {}"},
+			},
+		})
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	config.URL = server.URL
+	return NewSourcify(config, NewCache(time.Minute))
+}
+
+func TestSourcifyGetMatch(t *testing.T) {
+	s := newFakeSourcify(t, SourcifyFullMatch, SourcifyConfig{ChainID: 1})
+
+	match, err := s.GetMatch("0xabc")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if match.Status != SourcifyFullMatch {
+		t.Errorf("Expected full match, got %s", match.Status)
+	}
+	if match.ABI != `[{"type":"event","name":"Transfer","inputs":[]}]` {
+		t.Errorf("Unexpected ABI %s", match.ABI)
+	}
+	if match.Sources["contracts/Token.sol"] != "contract Token {}" {
+		t.Errorf("Expected source keyed by original path, got %v", match.Sources)
+	}
+}
+
+func TestSourcifyPartialMatch(t *testing.T) {
+	s := newFakeSourcify(t, SourcifyPartialMatch, SourcifyConfig{ChainID: 1})
+	if _, err := s.GetContractABI("0xabc"); err != nil {
+		t.Errorf("Expected partial match to be accepted, got %v", err)
+	}
+
+	strict := newFakeSourcify(t, SourcifyPartialMatch, SourcifyConfig{ChainID: 1, FullMatchOnly: true})
+	if _, err := strict.GetContractABI("0xabc"); err == nil {
+		t.Error("Expected partial match to be rejected with FullMatchOnly, got nil")
+	}
+}
+
+func TestSourcifyNotFound(t *testing.T) {
+	s := newFakeSourcify(t, SourcifyFullMatch, SourcifyConfig{ChainID: 5})
+	if _, err := s.GetContractABI("0xabc"); err == nil {
+		t.Error("Expected error for unverified contract, got nil")
+	}
+}
@@ -0,0 +1,245 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	subscriber = common.HexToAddress("0x0000000000000000000000000000000000000a11")
+	recipient  = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+)
+
+func (f *fakeChainClient) addLogs(latest uint64, logs ...types.Log) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logs = append(f.logs, logs...)
+	f.latest = latest
+}
+
+// replaceBlock swaps the logs of a block, as a reorganization does
+func (f *fakeChainClient) replaceBlock(block uint64, logs ...types.Log) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var kept []types.Log
+	for _, log := range f.logs {
+		if log.BlockNumber != block {
+			kept = append(kept, log)
+		}
+	}
+	f.logs = append(kept, logs...)
+}
+
+type fakeSubscription struct {
+	errs chan error
+}
+
+func (s *fakeSubscription) Unsubscribe()      {}
+func (s *fakeSubscription) Err() <-chan error { return s.errs }
+
+// fakeWSClient delivers logs pushed by the test to the current subscription
+type fakeWSClient struct {
+	*fakeChainClient
+	subscribed chan chan<- types.Log
+	subs       chan *fakeSubscription
+}
+
+func (f *fakeWSClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	sub := &fakeSubscription{errs: make(chan error, 1)}
+	f.subscribed <- ch
+	f.subs <- sub
+	return sub, nil
+}
+
+type httpOnlyClient struct {
+	*fakeChainClient
+}
+
+func (f *httpOnlyClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return nil, rpc.ErrNotificationsUnsupported
+}
+
+func withBlockHash(log types.Log, hash string) types.Log {
+	log.BlockHash = common.HexToHash(hash)
+	return log
+}
+
+func receive(t *testing.T, sub *EventSubscription, n int) []EventRecord {
+	t.Helper()
+	var records []EventRecord
+	for len(records) < n {
+		select {
+		case record := <-sub.Events():
+			records = append(records, record)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out after %d of %d events", len(records), n)
+		}
+	}
+	return records
+}
+
+func expectNoEvents(t *testing.T, sub *EventSubscription) {
+	t.Helper()
+	select {
+	case record := <-sub.Events():
+		t.Fatalf("Unexpected event %+v", record)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscribeEventsPolling(t *testing.T) {
+	chain := &fakeChainClient{latest: 102, logs: []types.Log{
+		transferLog(t, 99, 0, subscriber, recipient, 1),
+		transferLog(t, 100, 0, subscriber, recipient, 2),
+		withBlockHash(transferLog(t, 102, 0, subscriber, recipient, 3), "0x102a"),
+	}}
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, &httpOnlyClient{chain}, NewCache(time.Minute))
+	contracts.SetSubscriptionConfig(SubscriptionConfig{PollInterval: 10 * time.Millisecond, ReorgDepth: 5})
+
+	sub, err := contracts.SubscribeEvents(tokenAddress, "Transfer", EventFilter{FromBlock: 100}, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	backfill := receive(t, sub, 2)
+	if backfill[0].BlockNumber != 100 || backfill[1].BlockNumber != 102 {
+		t.Errorf("Unexpected backfill %+v", backfill)
+	}
+	expectNoEvents(t, sub)
+
+	chain.addLogs(104, transferLog(t, 104, 0, subscriber, recipient, 4))
+	if record := receive(t, sub, 1)[0]; record.BlockNumber != 104 {
+		t.Errorf("Expected block 104, got %d", record.BlockNumber)
+	}
+
+	// Block 102 is replaced by a block with a different transfer
+	chain.replaceBlock(102, withBlockHash(transferLog(t, 102, 0, subscriber, recipient, 30), "0x102b"))
+	records := receive(t, sub, 2)
+	if !records[0].Removed || records[0].Args["value"].(*big.Int).Int64() != 3 {
+		t.Errorf("Expected removal of the old transfer, got %+v", records[0])
+	}
+	if records[1].Removed || records[1].Args["value"].(*big.Int).Int64() != 30 {
+		t.Errorf("Expected the new transfer, got %+v", records[1])
+	}
+	expectNoEvents(t, sub)
+}
+
+func TestSubscribeEventsWebsocketResume(t *testing.T) {
+	chain := &fakeChainClient{latest: 100, logs: []types.Log{
+		transferLog(t, 100, 0, subscriber, recipient, 1),
+	}}
+	client := &fakeWSClient{
+		fakeChainClient: chain,
+		subscribed:      make(chan chan<- types.Log, 1),
+		subs:            make(chan *fakeSubscription, 1),
+	}
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, client, NewCache(time.Minute))
+	contracts.SetSubscriptionConfig(SubscriptionConfig{PollInterval: 10 * time.Millisecond})
+
+	sub, err := contracts.SubscribeEvents(tokenAddress, "Transfer", EventFilter{FromBlock: 100}, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	live := <-client.subscribed
+	first := <-client.subs
+	receive(t, sub, 1)
+
+	// A live log, then the same log again as the subscription may repeat logs of the backfill
+	log101 := transferLog(t, 101, 0, subscriber, recipient, 2)
+	chain.addLogs(101, log101)
+	live <- log101
+	live <- log101
+	if record := receive(t, sub, 1)[0]; record.BlockNumber != 101 {
+		t.Errorf("Expected block 101, got %d", record.BlockNumber)
+	}
+	expectNoEvents(t, sub)
+
+	// A reorg removes the log
+	removed := log101
+	removed.Removed = true
+	live <- removed
+	if record := receive(t, sub, 1)[0]; !record.Removed || record.BlockNumber != 101 {
+		t.Errorf("Expected removed log of block 101, got %+v", record)
+	}
+	chain.replaceBlock(101)
+
+	// Blocks mined while disconnected are backfilled after resubscribing
+	chain.addLogs(103, transferLog(t, 102, 0, subscriber, recipient, 3), transferLog(t, 103, 0, subscriber, recipient, 4))
+	first.errs <- errors.New("connection reset")
+	<-client.subscribed
+	<-client.subs
+
+	records := receive(t, sub, 2)
+	if records[0].BlockNumber != 102 || records[1].BlockNumber != 103 {
+		t.Errorf("Expected blocks 102 and 103 after reconnecting, got %+v", records)
+	}
+	expectNoEvents(t, sub)
+
+	select {
+	case err := <-sub.Err():
+		if err == nil {
+			t.Error("Expected the disconnect to be reported")
+		}
+	default:
+		t.Error("Expected the disconnect to be reported")
+	}
+}
+
+func TestSubscribeEventsSkipsUndecodableLog(t *testing.T) {
+	// An ERC-721 Transfer shares the topic of the ERC-20 one, with the token ID indexed
+	nft := transferLog(t, 101, 0, subscriber, recipient, 1)
+	nft.Topics = append(nft.Topics, common.BigToHash(big.NewInt(7)))
+	nft.Data = nil
+	chain := &fakeChainClient{latest: 102, logs: []types.Log{
+		transferLog(t, 100, 0, subscriber, recipient, 1),
+		nft,
+		transferLog(t, 102, 0, subscriber, recipient, 2),
+	}}
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, &httpOnlyClient{chain}, NewCache(time.Minute))
+	contracts.SetSubscriptionConfig(SubscriptionConfig{PollInterval: 10 * time.Millisecond, ReorgDepth: 5})
+
+	sub, err := contracts.SubscribeEvents(tokenAddress, "Transfer", EventFilter{FromBlock: 100}, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	records := receive(t, sub, 2)
+	if records[0].BlockNumber != 100 || records[1].BlockNumber != 102 {
+		t.Errorf("Expected the logs around the undecodable one, got %+v", records)
+	}
+	select {
+	case err := <-sub.Err():
+		if !strings.Contains(err.Error(), "failed to decode") {
+			t.Errorf("Expected a decode error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the undecodable log to be reported")
+	}
+
+	// The stream keeps following the chain
+	chain.addLogs(103, transferLog(t, 103, 0, subscriber, recipient, 3))
+	if record := receive(t, sub, 1)[0]; record.BlockNumber != 103 {
+		t.Errorf("Expected block 103, got %d", record.BlockNumber)
+	}
+	expectNoEvents(t, sub)
+}
+
+func TestSubscribeEventsRejectsToBlock(t *testing.T) {
+	contracts := NewContracts(&fakeProvider{abi: erc20ABI}, &fakeChainClient{}, NewCache(time.Minute))
+	if _, err := contracts.SubscribeEvents(tokenAddress, "Transfer", EventFilter{ToBlock: 10}, false); err == nil {
+		t.Error("Expected error for a subscription with toBlock, got nil")
+	}
+}
@@ -0,0 +1,234 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const testPrivateKey = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+// sendingClient accepts transactions and serves receipts, advancing the head on every poll
+type sendingClient struct {
+	*fakeStateClient
+	mu          sync.Mutex
+	head        uint64
+	sent        []*types.Transaction
+	receipts    map[common.Hash]*types.Receipt
+	estimateErr error
+	replayErr   error
+}
+
+func newSendingClient() *sendingClient {
+	return &sendingClient{fakeStateClient: newFakeStateClient(), receipts: make(map[common.Hash]*types.Receipt)}
+}
+
+func (s *sendingClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (s *sendingClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return 7, nil
+}
+
+func (s *sendingClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return 51000, s.estimateErr
+}
+
+func (s *sendingClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, tx)
+	return nil
+}
+
+func (s *sendingClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	receipt, ok := s.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (s *sendingClient) BlockNumber(ctx context.Context) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.head++
+	return s.head, nil
+}
+
+func (s *sendingClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if s.replayErr != nil {
+		return nil, s.replayErr
+	}
+	return s.fakeStateClient.CallContract(ctx, call, blockNumber)
+}
+
+func (s *sendingClient) mine(tx *types.Transaction, block uint64, status uint64, logs ...types.Log) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	receipt := &types.Receipt{Status: status, TxHash: tx.Hash(), BlockNumber: new(big.Int).SetUint64(block), GasUsed: 35000}
+	for i := range logs {
+		logs[i].TxHash = tx.Hash()
+		receipt.Logs = append(receipt.Logs, &logs[i])
+	}
+	s.receipts[tx.Hash()] = receipt
+}
+
+func transactingContracts(client *sendingClient) *Contracts {
+	history := &ethereum.FeeHistory{
+		Reward:       [][]*big.Int{{big.NewInt(1e9), big.NewInt(2e9), big.NewInt(3e9)}},
+		BaseFee:      []*big.Int{big.NewInt(10e9), big.NewInt(10e9)},
+		GasUsedRatio: []float64{0.5},
+	}
+	accounts := NewAccounts()
+	accounts.SetTransactionBuilder(client, NewFeeOracle(nil, &fakeFeeHistory{history: history}))
+	contracts := proxyContracts(client, mapProvider{strings.ToLower(tokenAddress): erc20ABI})
+	contracts.SetAccounts(accounts)
+	return contracts
+}
+
+func TestTransact(t *testing.T) {
+	client := newSendingClient()
+	contracts := transactingContracts(client)
+	account := &Account{PrivateKey: testPrivateKey}
+
+	pending, err := contracts.Transact(account, tokenAddress, "transfer", []interface{}{recipient, big.NewInt(100)}, TransactOpts{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(client.sent) != 1 || client.sent[0].Hash() != pending.Hash {
+		t.Fatalf("Expected the transaction to be sent, got %v", client.sent)
+	}
+	tx := client.sent[0]
+	if *tx.To() != common.HexToAddress(tokenAddress) || tx.Nonce() != 7 || tx.Gas() != 51000 {
+		t.Errorf("Unexpected transaction to %s with nonce %d and gas %d", tx.To().Hex(), tx.Nonce(), tx.Gas())
+	}
+	if !strings.HasPrefix(hexutil.Encode(tx.Data()), "0xa9059cbb") {
+		t.Errorf("Unexpected calldata %x", tx.Data())
+	}
+
+	client.head = 99
+	client.mine(tx, 100, types.ReceiptStatusSuccessful, transferLog(t, 100, 0, pending.From, recipient, 100))
+	receipt, err := pending.Wait(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if receipt.Confirmations != 3 || client.head != 102 {
+		t.Errorf("Expected 3 confirmations at block 102, got %d at %d", receipt.Confirmations, client.head)
+	}
+	if len(receipt.Events) != 1 || receipt.Events[0].Event != "Transfer" {
+		t.Fatalf("Expected a Transfer event, got %+v", receipt.Events)
+	}
+	if value := receipt.Events[0].Args["value"].(*big.Int); value.Int64() != 100 {
+		t.Errorf("Expected value 100, got %v", value)
+	}
+}
+
+func TestTransactOpts(t *testing.T) {
+	client := newSendingClient()
+	contracts := transactingContracts(client)
+	account := &Account{PrivateKey: testPrivateKey}
+
+	nonce := uint64(42)
+	opts := TransactOpts{GasLimit: 80000, Nonce: &nonce, MaxFeePerGas: big.NewInt(50e9), MaxPriorityFeePerGas: big.NewInt(1e9)}
+	if _, err := contracts.Transact(account, tokenAddress, "transfer", []interface{}{recipient, big.NewInt(1)}, opts); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tx := client.sent[0]
+	if tx.Nonce() != 42 || tx.Gas() != 80000 || tx.GasFeeCap().Int64() != 50e9 || tx.GasTipCap().Int64() != 1e9 {
+		t.Errorf("Expected options to be used, got nonce %d, gas %d, fees %v/%v", tx.Nonce(), tx.Gas(), tx.GasFeeCap(), tx.GasTipCap())
+	}
+
+	if _, err := contracts.Transact(account, tokenAddress, "transfer", []interface{}{recipient, big.NewInt(1)}, TransactOpts{Value: big.NewInt(1)}); err == nil {
+		t.Error("Expected error for value sent to a non-payable function")
+	}
+	noAccounts := proxyContracts(client, mapProvider{strings.ToLower(tokenAddress): erc20ABI})
+	if _, err := noAccounts.Transact(account, tokenAddress, "transfer", []interface{}{recipient, big.NewInt(1)}, TransactOpts{}); err == nil {
+		t.Error("Expected error without accounts")
+	}
+}
+
+func TestTransactReverts(t *testing.T) {
+	client := newSendingClient()
+	contracts := transactingContracts(client)
+	account := &Account{PrivateKey: testPrivateKey}
+	insufficient := &fakeDataError{data: hexutil.Encode(packRevert(t, "InsufficientBalance(uint256,uint256)", big.NewInt(1), big.NewInt(100)))}
+
+	// Reverts during estimation are decoded with the contract ABI and nothing is sent
+	client.estimateErr = insufficient
+	_, err := contracts.Transact(account, tokenAddress, "transfer", []interface{}{recipient, big.NewInt(100)}, TransactOpts{})
+	var revert *RevertError
+	if !errors.As(err, &revert) || revert.Name != "InsufficientBalance" {
+		t.Errorf("Expected InsufficientBalance revert, got %v", err)
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("Expected nothing to be sent, got %d transactions", len(client.sent))
+	}
+
+	// Failed transactions are replayed at their block for the reason
+	client.estimateErr = nil
+	pending, err := contracts.Transact(account, tokenAddress, "transfer", []interface{}{recipient, big.NewInt(100)}, TransactOpts{GasLimit: 60000, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	client.mine(client.sent[0], 1, types.ReceiptStatusFailed)
+	client.replayErr = insufficient
+	receipt, err := pending.Wait(context.Background(), 1)
+	if receipt == nil || receipt.Receipt.Status != types.ReceiptStatusFailed {
+		t.Fatalf("Expected the failed receipt, got %+v", receipt)
+	}
+	if !errors.As(err, &revert) || revert.Name != "InsufficientBalance" || !strings.Contains(err.Error(), pending.Hash.Hex()) {
+		t.Errorf("Expected InsufficientBalance revert of %s, got %v", pending.Hash.Hex(), err)
+	}
+}
+
+func TestTransactWaitUndecodableLog(t *testing.T) {
+	client := newSendingClient()
+	contracts := transactingContracts(client)
+	pending, err := contracts.Transact(&Account{PrivateKey: testPrivateKey}, tokenAddress, "transfer", []interface{}{recipient, big.NewInt(1)}, TransactOpts{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// An ERC-721 Transfer shares the topic of the ERC-20 one, with the token ID indexed
+	transfer := transferLog(t, 1, 1, pending.From, recipient, 1)
+	nft := transferLog(t, 1, 0, pending.From, recipient, 1)
+	nft.Topics = append(nft.Topics, common.BigToHash(big.NewInt(7)))
+	nft.Data = nil
+	client.mine(client.sent[0], 1, types.ReceiptStatusSuccessful, nft, transfer)
+
+	receipt, err := pending.Wait(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Expected no error for a successful transaction, got %v", err)
+	}
+	if len(receipt.Events) != 1 || receipt.Events[0].LogIndex != 1 {
+		t.Errorf("Expected the undecodable log to be skipped, got %+v", receipt.Events)
+	}
+}
+
+func TestTransactWaitCancelled(t *testing.T) {
+	client := newSendingClient()
+	contracts := transactingContracts(client)
+	pending, err := contracts.Transact(&Account{PrivateKey: testPrivateKey}, tokenAddress, "transfer", []interface{}{recipient, big.NewInt(1)}, TransactOpts{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pending.Wait(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded while pending, got %v", err)
+	}
+}
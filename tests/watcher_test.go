@@ -0,0 +1,301 @@
+package ethereal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// watchClient is a chain with a mempool whose state tests change between polls
+type watchClient struct {
+	*fakeStateClient
+	mu       sync.Mutex
+	head     uint64
+	pool     map[common.Hash]*types.Transaction
+	receipts map[common.Hash]*types.Receipt
+	nonces   map[common.Address]uint64
+	blocks   map[uint64]*types.Block
+	// receiptErrs fails receipt reads of some transactions
+	receiptErrs map[common.Hash]error
+}
+
+func newWatchClient() *watchClient {
+	return &watchClient{
+		fakeStateClient: newFakeStateClient(),
+		head:            10,
+		pool:            make(map[common.Hash]*types.Transaction),
+		receipts:        make(map[common.Hash]*types.Receipt),
+		nonces:          make(map[common.Address]uint64),
+		blocks:          make(map[uint64]*types.Block),
+	}
+}
+
+func (w *watchClient) BlockNumber(ctx context.Context) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.head, nil
+}
+
+func (w *watchClient) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err, ok := w.receiptErrs[hash]; ok {
+		return nil, err
+	}
+	if receipt, ok := w.receipts[hash]; ok {
+		return receipt, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+func (w *watchClient) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if tx, ok := w.pool[hash]; ok {
+		return tx, true, nil
+	}
+	return nil, false, ethereum.NotFound
+}
+
+func (w *watchClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.nonces[account], nil
+}
+
+func (w *watchClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if block, ok := w.blocks[number.Uint64()]; ok {
+		return block, nil
+	}
+	return types.NewBlockWithHeader(&types.Header{Number: number}), nil
+}
+
+func (w *watchClient) update(change func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	change()
+}
+
+// include puts transactions in a block, without receipts for them
+func (w *watchClient) include(number uint64, txs ...*types.Transaction) {
+	w.blocks[number] = types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(number)}).WithBody(txs, nil)
+}
+
+func signedTx(t *testing.T, nonce uint64, to common.Address, value int64, data []byte, tip int64) *types.Transaction {
+	t.Helper()
+	tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(tip),
+		GasFeeCap: big.NewInt(100e9),
+		Gas:       60000,
+		To:        &to,
+		Value:     big.NewInt(value),
+		Data:      data,
+	}), types.LatestSignerForChainID(big.NewInt(1)), testKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func watchingContracts(client *watchClient, config WatcherConfig) *Contracts {
+	contracts := proxyContracts(client, mapProvider{})
+	config.PollInterval = time.Millisecond
+	contracts.SetWatcherConfig(config)
+	return contracts
+}
+
+func nextUpdate(t *testing.T, watcher *TxWatcher) TxUpdate {
+	t.Helper()
+	select {
+	case update := <-watcher.Updates():
+		return update
+	case err := <-watcher.Err():
+		t.Fatalf("Unexpected watcher error %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an update")
+	}
+	return TxUpdate{}
+}
+
+func TestWatchTransactionsLifecycle(t *testing.T) {
+	client := newWatchClient()
+	tx := signedTx(t, 3, recipient, 1, nil, 1e9)
+	client.pool[tx.Hash()] = tx
+	watcher, err := watchingContracts(client, WatcherConfig{Confirmations: 3}).WatchTransactions(tx.Hash().Hex())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer watcher.Stop()
+
+	if update := nextUpdate(t, watcher); update.Status != TxPending || update.Hash != tx.Hash() {
+		t.Fatalf("Expected pending, got %+v", update)
+	}
+
+	first := &types.Receipt{TxHash: tx.Hash(), BlockNumber: big.NewInt(10), BlockHash: common.HexToHash("0x0a"), Status: 1}
+	client.update(func() { client.receipts[tx.Hash()] = first })
+	if update := nextUpdate(t, watcher); update.Status != TxMined || update.Confirmations != 1 {
+		t.Fatalf("Expected mined with 1 confirmation, got %+v", update)
+	}
+
+	// A reorganization drops the block, and the transaction is mined again in another one
+	client.update(func() { delete(client.receipts, tx.Hash()) })
+	if update := nextUpdate(t, watcher); update.Status != TxRemoved || update.Receipt != first {
+		t.Fatalf("Expected removed with the old receipt, got %+v", update)
+	}
+	second := &types.Receipt{TxHash: tx.Hash(), BlockNumber: big.NewInt(11), BlockHash: common.HexToHash("0x0b"), Status: 1}
+	client.update(func() {
+		client.head = 11
+		client.receipts[tx.Hash()] = second
+	})
+	if update := nextUpdate(t, watcher); update.Status != TxMined || update.Receipt != second {
+		t.Fatalf("Expected mined again, got %+v", update)
+	}
+
+	client.update(func() { client.head = 13 })
+	if update := nextUpdate(t, watcher); update.Status != TxConfirmed || update.Confirmations != 3 {
+		t.Fatalf("Expected confirmed with 3 confirmations, got %+v", update)
+	}
+
+	// Confirmed transactions are no longer watched
+	client.update(func() { delete(client.receipts, tx.Hash()) })
+	select {
+	case update := <-watcher.Updates():
+		t.Errorf("Unexpected update %+v", update)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestWatchTransactionsReplaced(t *testing.T) {
+	client := newWatchClient()
+	sender := crypto.PubkeyToAddress(testKey(t).PublicKey)
+	slow := signedTx(t, 5, recipient, 1, []byte{0x01}, 1e9)
+	cancelled := signedTx(t, 6, recipient, 1, []byte{0x02}, 1e9)
+	client.pool[slow.Hash()] = slow
+	client.pool[cancelled.Hash()] = cancelled
+	client.nonces[sender] = 5
+
+	watcher, err := watchingContracts(client, WatcherConfig{}).WatchTransactions(slow.Hash().Hex(), cancelled.Hash().Hex())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer watcher.Stop()
+	for i := 0; i < 2; i++ {
+		if update := nextUpdate(t, watcher); update.Status != TxPending {
+			t.Fatalf("Expected pending, got %+v", update)
+		}
+	}
+
+	speedUp := signedTx(t, 5, recipient, 1, []byte{0x01}, 3e9)
+	cancel := signedTx(t, 6, sender, 0, nil, 3e9)
+	client.update(func() {
+		delete(client.pool, slow.Hash())
+		delete(client.pool, cancelled.Hash())
+		client.nonces[sender] = 7
+		client.head = 12
+		client.include(11, speedUp)
+		client.include(12, cancel)
+	})
+
+	updates := make(map[common.Hash]TxUpdate)
+	for i := 0; i < 2; i++ {
+		update := nextUpdate(t, watcher)
+		updates[update.Hash] = update
+	}
+	if update := updates[slow.Hash()]; update.Status != TxReplaced || update.Replacement != ReplacementSpeedUp || *update.ReplacedBy != speedUp.Hash() {
+		t.Errorf("Expected speed-up by %s, got %+v", speedUp.Hash().Hex(), update)
+	}
+	if update := updates[cancelled.Hash()]; update.Status != TxReplaced || update.Replacement != ReplacementCancel || *update.ReplacedBy != cancel.Hash() {
+		t.Errorf("Expected cancellation by %s, got %+v", cancel.Hash().Hex(), update)
+	}
+}
+
+func TestWatchTransactionsDropped(t *testing.T) {
+	client := newWatchClient()
+	received := make(chan TxUpdate, 1)
+	contracts := watchingContracts(client, WatcherConfig{
+		DropAfter: 10 * time.Millisecond,
+		OnUpdate:  func(update TxUpdate) { received <- update },
+	})
+
+	hash := "0x" + strings.Repeat("ab", 32)
+	watcher, err := contracts.WatchTransactions(hash)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer watcher.Stop()
+
+	select {
+	case update := <-received:
+		if update.Status != TxDropped || update.Hash != common.HexToHash(hash) {
+			t.Errorf("Expected dropped, got %+v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the transaction to be dropped")
+	}
+
+	if err := watcher.Watch("0x1234"); err == nil {
+		t.Error("Expected error for an invalid hash")
+	}
+}
+
+func TestWatchTransactionsFailingTransaction(t *testing.T) {
+	client := newWatchClient()
+	tx := signedTx(t, 3, recipient, 1, nil, 1e9)
+	client.pool[tx.Hash()] = tx
+	hashes := []string{tx.Hash().Hex()}
+	client.receiptErrs = make(map[common.Hash]error)
+	for i := 1; i <= 16; i++ {
+		failing := common.BigToHash(big.NewInt(int64(i)))
+		client.receiptErrs[failing] = errors.New("connection reset")
+		hashes = append(hashes, failing.Hex())
+	}
+
+	// A single poll reaches the transaction whatever the order
+	contracts := watchingContracts(client, WatcherConfig{})
+	contracts.SetWatcherConfig(WatcherConfig{PollInterval: time.Hour})
+	watcher, err := contracts.WatchTransactions(hashes...)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer watcher.Stop()
+
+	select {
+	case update := <-watcher.Updates():
+		if update.Status != TxPending || update.Hash != tx.Hash() {
+			t.Errorf("Expected pending, got %+v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the transaction without errors")
+	}
+	select {
+	case err := <-watcher.Err():
+		if !strings.Contains(err.Error(), "connection reset") {
+			t.Errorf("Expected the receipt error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the receipt error")
+	}
+}
+
+func testKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(testPrivateKey, "0x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
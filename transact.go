@@ -0,0 +1,255 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const defaultReceiptPollInterval = 2 * time.Second
+
+// TransactionSender interface defines the method required to broadcast transactions
+type TransactionSender interface {
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
+// ReceiptClient interface defines the methods required to wait for receipts
+type ReceiptClient interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// TransactOpts configures a contract transaction; unset fields are filled in from the node
+type TransactOpts struct {
+	// Value is the amount of wei sent with the call
+	Value *big.Int
+	// GasLimit is estimated when 0
+	GasLimit uint64
+	// Nonce is the pending nonce of the account when nil
+	Nonce *uint64
+	// MaxFeePerGas and MaxPriorityFeePerGas default to the standard fee suggestion
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	// ResolveProxy encodes the call through the implementation ABI of a proxy
+	ResolveProxy bool
+	// PollInterval is the delay between receipt checks of Wait
+	PollInterval time.Duration
+}
+
+// PendingTransaction is a broadcast transaction
+type PendingTransaction struct {
+	Hash common.Hash
+	Tx   *types.Transaction
+	From common.Address
+
+	contracts    *Contracts
+	abis         []*ContractABI
+	pollInterval time.Duration
+}
+
+// TransactionReceipt is the receipt of a mined transaction with its decoded events
+type TransactionReceipt struct {
+	Receipt       *types.Receipt
+	Confirmations uint64
+	// Events holds the logs of contracts whose ABI is known, decoded
+	Events []EventRecord
+}
+
+// SetAccounts configures the accounts Transact signs with
+func (c *Contracts) SetAccounts(accounts *Accounts) {
+	c.accounts = accounts
+}
+
+// Transact sends a transaction calling a state-changing function. The calldata is
+// encoded as by EncodeFunctionCall, and the gas limit, fees and nonce are filled in
+// unless set in opts. Gas estimation reverts are returned as a *RevertError.
+func (c *Contracts) Transact(account *Account, address string, method string, args []interface{}, opts TransactOpts) (*PendingTransaction, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid address %s", address)
+	}
+	call, err := c.prepareCall(CallRequest{Address: address, Method: method, Args: args}, opts.ResolveProxy)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Value != nil && opts.Value.Sign() > 0 && !call.method.IsPayable() {
+		return nil, fmt.Errorf("%s is not payable", call.method.Sig)
+	}
+
+	abis := append(call.abis, c.errorABIs(address)...)
+	to := call.to
+	return c.sendTransaction(account, transactionRequest{
+		to:                   &to,
+		value:                opts.Value,
+		data:                 call.data,
+		gas:                  opts.GasLimit,
+		nonce:                opts.Nonce,
+		maxFeePerGas:         opts.MaxFeePerGas,
+		maxPriorityFeePerGas: opts.MaxPriorityFeePerGas,
+		abis:                 abis,
+	}, opts.PollInterval)
+}
+
+// sendTransaction builds, signs and broadcasts a transaction
+func (c *Contracts) sendTransaction(account *Account, req transactionRequest, pollInterval time.Duration) (*PendingTransaction, error) {
+	if c.accounts == nil {
+		return nil, errors.New("no accounts configured")
+	}
+	sender, ok := c.client.(TransactionSender)
+	if !ok {
+		return nil, errNoRPCClient
+	}
+	ctx := context.Background()
+
+	tx, err := c.accounts.buildTransaction(ctx, account, req)
+	if err != nil {
+		return nil, err
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender: %w", err)
+	}
+	if err := sender.SendTransaction(ctx, tx); err != nil {
+		return nil, fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	if pollInterval <= 0 {
+		pollInterval = defaultReceiptPollInterval
+	}
+	return &PendingTransaction{
+		Hash:         tx.Hash(),
+		Tx:           tx,
+		From:         from,
+		contracts:    c,
+		abis:         req.abis,
+		pollInterval: pollInterval,
+	}, nil
+}
+
+// Wait waits until the transaction is mined with the given number of confirmations,
+// counting its own block as the first, and decodes its events. A receipt that
+// disappears in a reorganization is waited for again. When the transaction failed,
+// the receipt is returned with an error, a *RevertError when the revert reason can be
+// recovered by replaying the call at the block it was mined in.
+func (p *PendingTransaction) Wait(ctx context.Context, confirmations uint64) (*TransactionReceipt, error) {
+	client, ok := p.contracts.client.(ReceiptClient)
+	if !ok {
+		return nil, errNoRPCClient
+	}
+	if confirmations == 0 {
+		confirmations = 1
+	}
+
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+	for {
+		receipt, depth, err := p.confirmations(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		if receipt != nil && depth >= confirmations {
+			return p.finish(ctx, receipt, depth)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// confirmations gets the receipt and the number of blocks including it, or no receipt
+// while the transaction is pending
+func (p *PendingTransaction) confirmations(ctx context.Context, client ReceiptClient) (*types.Receipt, uint64, error) {
+	receipt, err := client.TransactionReceipt(ctx, p.Hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get receipt of %s: %w", p.Hash.Hex(), err)
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get block number: %w", err)
+	}
+	mined := receipt.BlockNumber.Uint64()
+	if head < mined {
+		// The node serving the receipt is ahead of the one serving the head
+		return receipt, 0, nil
+	}
+	return receipt, head - mined + 1, nil
+}
+
+func (p *PendingTransaction) finish(ctx context.Context, receipt *types.Receipt, depth uint64) (*TransactionReceipt, error) {
+	result := &TransactionReceipt{Receipt: receipt, Confirmations: depth}
+	if receipt.Status == types.ReceiptStatusFailed {
+		return result, p.revertReason(ctx, receipt)
+	}
+	result.Events = p.contracts.decodeReceiptLogs(receipt.Logs)
+	return result, nil
+}
+
+// revertReason replays a failed transaction at its block to recover the revert data
+func (p *PendingTransaction) revertReason(ctx context.Context, receipt *types.Receipt) error {
+	failed := fmt.Errorf("transaction %s failed", p.Hash.Hex())
+	state, ok := p.contracts.client.(StateClient)
+	if !ok {
+		return failed
+	}
+	msg := ethereum.CallMsg{
+		From:  p.From,
+		To:    p.Tx.To(),
+		Gas:   p.Tx.Gas(),
+		Value: p.Tx.Value(),
+		Data:  p.Tx.Data(),
+	}
+	_, err := state.CallContract(ctx, msg, receipt.BlockNumber)
+	if _, ok := revertData(err); !ok {
+		// The call succeeds when the state it failed on changed within the block
+		return failed
+	}
+	return fmt.Errorf("transaction %s failed: %w", p.Hash.Hex(), revertError(err, p.abis...))
+}
+
+// decodeReceiptLogs decodes the logs of contracts whose ABI is known, skipping the others
+// and logs that do not decode with it, such as those of a stale ABI. Events are decoded
+// without a timestamp when the block cannot be read.
+func (c *Contracts) decodeReceiptLogs(logs []*types.Log) []EventRecord {
+	abis := make(map[common.Address]*ContractABI)
+	timestamps := make(map[uint64]time.Time)
+	var records []EventRecord
+	for _, log := range logs {
+		if len(log.Topics) == 0 {
+			continue
+		}
+		contractABI, seen := abis[log.Address]
+		if !seen {
+			contractABI, _ = c.GetABI(log.Address.Hex(), true)
+			abis[log.Address] = contractABI
+		}
+		if contractABI == nil {
+			continue
+		}
+		event, err := contractABI.ABI.EventByID(log.Topics[0])
+		if err != nil {
+			continue
+		}
+		args, err := decodeEventLog(event, *log)
+		if err != nil {
+			continue
+		}
+		timestamp, seen := timestamps[log.BlockNumber]
+		if !seen {
+			timestamp, _ = c.blockTimestamp(log.BlockNumber)
+			timestamps[log.BlockNumber] = timestamp
+		}
+		records = append(records, eventRecord(event, *log, args, timestamp))
+	}
+	return records
+}
@@ -0,0 +1,422 @@
+package ethereal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	defaultWatcherPollInterval = 5 * time.Second
+	defaultConfirmations       = 12
+	defaultDropAfter           = 10 * time.Minute
+	// maxReplacementScan bounds the blocks searched for the transaction that took a nonce
+	maxReplacementScan = 256
+)
+
+// TxStatus is the state of a watched transaction
+type TxStatus string
+
+const (
+	// TxPending is reported once the node knows the transaction and it is not mined
+	TxPending TxStatus = "pending"
+	// TxMined is reported when a receipt appears, again after a reorganization
+	TxMined TxStatus = "mined"
+	// TxConfirmed is reported when the receipt has the configured confirmations; the
+	// transaction is no longer watched
+	TxConfirmed TxStatus = "confirmed"
+	// TxRemoved is reported when a reorganization removes the receipt; the transaction
+	// is pending again
+	TxRemoved TxStatus = "removed"
+	// TxReplaced is reported when another transaction of the sender with the same nonce
+	// is mined; the transaction is no longer watched
+	TxReplaced TxStatus = "replaced"
+	// TxDropped is reported when the node has not known the transaction for DropAfter
+	// and its nonce is still unused; the transaction is no longer watched
+	TxDropped TxStatus = "dropped"
+)
+
+// Kinds of replacements, comparing the replacement with the replaced transaction
+const (
+	ReplacementSpeedUp = "speedup" // same recipient, value and data
+	ReplacementCancel  = "cancel"  // empty transfer of the sender to itself
+	ReplacementOther   = "other"
+)
+
+// WatcherClient interface defines the methods required to watch transactions.
+// ethclient.Client satisfies it.
+type WatcherClient interface {
+	ReceiptClient
+	TransactionReader
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+}
+
+// WatcherConfig controls how transactions are watched
+type WatcherConfig struct {
+	PollInterval time.Duration
+	// Confirmations is the number of blocks, including its own, a receipt needs to be confirmed
+	Confirmations uint64
+	// DropAfter is how long a transaction can be unknown to the node before it is dropped
+	DropAfter time.Duration
+	// OnUpdate receives updates instead of the Updates channel when set. It is called
+	// from the watcher's goroutine and delays polling until it returns.
+	OnUpdate func(TxUpdate)
+}
+
+// TxUpdate is a change in the state of a watched transaction
+type TxUpdate struct {
+	Hash   common.Hash
+	Status TxStatus
+	// Receipt is set when mined, confirmed and removed; removed updates carry the old receipt
+	Receipt       *types.Receipt
+	Confirmations uint64
+	// ReplacedBy is the transaction that took the nonce of a replaced transaction, if found
+	ReplacedBy  *common.Hash
+	Replacement string
+}
+
+// TxWatcher follows transactions until they are confirmed, replaced or dropped
+type TxWatcher struct {
+	client WatcherClient
+	config WatcherConfig
+
+	mu      sync.Mutex
+	watched map[common.Hash]*watchedTx
+
+	updates chan TxUpdate
+	errs    chan error
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// watchedTx is what the watcher knows about a transaction
+type watchedTx struct {
+	hash     common.Hash
+	tx       *types.Transaction // nil until the node returns it
+	from     common.Address
+	status   TxStatus
+	receipt  *types.Receipt
+	lastSeen time.Time
+	since    uint64 // the head when watching started, where replacements are searched from
+}
+
+// SetWatcherConfig configures how WatchTransactions follows transactions; zero values keep the defaults
+func (c *Contracts) SetWatcherConfig(config WatcherConfig) {
+	c.watcherConfig = config
+}
+
+// WatchTransactions follows transactions by hash, reporting when they are pending,
+// mined, confirmed, replaced by another transaction with the same nonce, dropped, or
+// removed from the chain by a reorganization before they are confirmed. More
+// transactions can be added with Watch.
+func (c *Contracts) WatchTransactions(hashes ...string) (*TxWatcher, error) {
+	client, ok := c.client.(WatcherClient)
+	if !ok {
+		return nil, errNoRPCClient
+	}
+
+	config := c.watcherConfig
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultWatcherPollInterval
+	}
+	if config.Confirmations == 0 {
+		config.Confirmations = defaultConfirmations
+	}
+	if config.DropAfter <= 0 {
+		config.DropAfter = defaultDropAfter
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &TxWatcher{
+		client:  client,
+		config:  config,
+		watched: make(map[common.Hash]*watchedTx),
+		updates: make(chan TxUpdate),
+		errs:    make(chan error, 1),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	for _, hash := range hashes {
+		if err := w.Watch(hash); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	go w.run(ctx)
+
+	return w, nil
+}
+
+// Watch adds a transaction to the watcher
+func (w *TxWatcher) Watch(hash string) error {
+	if _, err := hexutil.Decode(hash); err != nil || len(hash) != 66 {
+		return fmt.Errorf("invalid transaction hash %s", hash)
+	}
+	txHash := common.HexToHash(hash)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.watched[txHash]; !ok {
+		w.watched[txHash] = &watchedTx{hash: txHash, lastSeen: time.Now()}
+	}
+	return nil
+}
+
+// Updates returns the channel of updates, unless WatcherConfig.OnUpdate is set. The
+// channel is closed on Stop.
+func (w *TxWatcher) Updates() <-chan TxUpdate {
+	return w.updates
+}
+
+// Err reports errors the watcher recovers from by polling again. Errors are dropped
+// while a previous one has not been received.
+func (w *TxWatcher) Err() <-chan error {
+	return w.errs
+}
+
+// Stop stops the watcher and waits for it to finish
+func (w *TxWatcher) Stop() {
+	w.cancel()
+	<-w.done
+}
+
+func (w *TxWatcher) run(ctx context.Context) {
+	defer close(w.done)
+	defer close(w.updates)
+
+	for {
+		if err := w.poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.report(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.config.PollInterval):
+		}
+	}
+}
+
+// poll checks every watched transaction against the latest block
+func (w *TxWatcher) poll(ctx context.Context) error {
+	head, err := w.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	w.mu.Lock()
+	watched := make([]*watchedTx, 0, len(w.watched))
+	for _, tx := range w.watched {
+		if tx.since == 0 {
+			tx.since = head
+		}
+		watched = append(watched, tx)
+	}
+	w.mu.Unlock()
+
+	// A failure for one transaction does not hold back the others
+	for _, tx := range watched {
+		if err := w.check(ctx, tx, head); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			w.report(err)
+		}
+	}
+	return nil
+}
+
+// report sends an error on Err without blocking
+func (w *TxWatcher) report(err error) {
+	select {
+	case w.errs <- err:
+	default:
+	}
+}
+
+// check moves a transaction to its current state, reporting every transition
+func (w *TxWatcher) check(ctx context.Context, tx *watchedTx, head uint64) error {
+	receipt, err := w.client.TransactionReceipt(ctx, tx.hash)
+	if err != nil && !errors.Is(err, ethereum.NotFound) {
+		return fmt.Errorf("failed to get receipt of %s: %w", tx.hash.Hex(), err)
+	}
+
+	if receipt != nil {
+		if tx.receipt != nil && tx.receipt.BlockHash != receipt.BlockHash {
+			// Mined again in another block after a reorganization
+			if err := w.emit(ctx, TxUpdate{Hash: tx.hash, Status: TxRemoved, Receipt: tx.receipt}); err != nil {
+				return err
+			}
+			tx.receipt = nil
+		}
+		confirmations := uint64(0)
+		if mined := receipt.BlockNumber.Uint64(); head >= mined {
+			confirmations = head - mined + 1
+		}
+		if tx.receipt == nil {
+			tx.receipt, tx.status = receipt, TxMined
+			if err := w.emit(ctx, TxUpdate{Hash: tx.hash, Status: TxMined, Receipt: receipt, Confirmations: confirmations}); err != nil {
+				return err
+			}
+		}
+		if confirmations >= w.config.Confirmations {
+			w.forget(tx)
+			return w.emit(ctx, TxUpdate{Hash: tx.hash, Status: TxConfirmed, Receipt: receipt, Confirmations: confirmations})
+		}
+		return nil
+	}
+
+	if tx.receipt != nil {
+		removed := tx.receipt
+		tx.receipt, tx.status = nil, TxPending
+		if err := w.emit(ctx, TxUpdate{Hash: tx.hash, Status: TxRemoved, Receipt: removed}); err != nil {
+			return err
+		}
+	}
+	return w.checkPending(ctx, tx)
+}
+
+// checkPending reports a transaction without receipt as pending, replaced or dropped
+func (w *TxWatcher) checkPending(ctx context.Context, tx *watchedTx) error {
+	known, _, err := w.client.TransactionByHash(ctx, tx.hash)
+	if err != nil && !errors.Is(err, ethereum.NotFound) {
+		return fmt.Errorf("failed to get transaction %s: %w", tx.hash.Hex(), err)
+	}
+	if known != nil {
+		tx.lastSeen = time.Now()
+		if tx.tx == nil {
+			from, err := txSender(known)
+			if err != nil {
+				return err
+			}
+			tx.tx, tx.from = known, from
+		}
+		if tx.status == "" {
+			tx.status = TxPending
+			if err := w.emit(ctx, TxUpdate{Hash: tx.hash, Status: TxPending}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// A transaction the node never returned cannot be matched by nonce
+	if tx.tx != nil {
+		nonce, err := w.client.NonceAt(ctx, tx.from, nil)
+		if err != nil {
+			return fmt.Errorf("failed to get nonce of %s: %w", tx.from.Hex(), err)
+		}
+		if nonce > tx.tx.Nonce() {
+			return w.replaced(ctx, tx)
+		}
+	}
+
+	if time.Since(tx.lastSeen) >= w.config.DropAfter {
+		w.forget(tx)
+		return w.emit(ctx, TxUpdate{Hash: tx.hash, Status: TxDropped})
+	}
+	return nil
+}
+
+// replaced searches the blocks since watching started for the transaction that took
+// the nonce. Finding the watched transaction itself means its receipt is not indexed yet.
+func (w *TxWatcher) replaced(ctx context.Context, tx *watchedTx) error {
+	// The nonce was read after the head of this poll, which may predate the replacement
+	head, err := w.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+	from := tx.since
+	if head > maxReplacementScan && from < head-maxReplacementScan {
+		from = head - maxReplacementScan
+	}
+
+	update := TxUpdate{Hash: tx.hash, Status: TxReplaced}
+	for number := head; number >= from && number > 0; number-- {
+		block, err := w.client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return fmt.Errorf("failed to get block %d: %w", number, err)
+		}
+		replacement := findNonce(block, tx.from, tx.tx.Nonce())
+		if replacement == nil {
+			continue
+		}
+		if replacement.Hash() == tx.hash {
+			return nil
+		}
+		hash := replacement.Hash()
+		update.ReplacedBy = &hash
+		update.Replacement = replacementKind(tx.tx, tx.from, replacement)
+		break
+	}
+
+	w.forget(tx)
+	return w.emit(ctx, update)
+}
+
+// findNonce finds the transaction of a sender with a nonce in a block
+func findNonce(block *types.Block, from common.Address, nonce uint64) *types.Transaction {
+	for _, candidate := range block.Transactions() {
+		if candidate.Nonce() != nonce {
+			continue
+		}
+		if sender, err := txSender(candidate); err == nil && sender == from {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func replacementKind(original *types.Transaction, from common.Address, replacement *types.Transaction) string {
+	sameTo := (original.To() == nil) == (replacement.To() == nil) &&
+		(original.To() == nil || *original.To() == *replacement.To())
+	if sameTo && original.Value().Cmp(replacement.Value()) == 0 && string(original.Data()) == string(replacement.Data()) {
+		return ReplacementSpeedUp
+	}
+	if replacement.To() != nil && *replacement.To() == from && replacement.Value().Sign() == 0 && len(replacement.Data()) == 0 {
+		return ReplacementCancel
+	}
+	return ReplacementOther
+}
+
+func txSender(tx *types.Transaction) (common.Address, error) {
+	signer := types.LatestSignerForChainID(tx.ChainId())
+	if !tx.Protected() {
+		signer = types.HomesteadSigner{}
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover sender of %s: %w", tx.Hash().Hex(), err)
+	}
+	return from, nil
+}
+
+func (w *TxWatcher) forget(tx *watchedTx) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.watched, tx.hash)
+}
+
+func (w *TxWatcher) emit(ctx context.Context, update TxUpdate) error {
+	if w.config.OnUpdate != nil {
+		w.config.OnUpdate(update)
+		return nil
+	}
+	select {
+	case w.updates <- update:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}